@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// DefaultBackfillWorkers is the number of block ranges a backfill scans in parallel.
+const DefaultBackfillWorkers = 4
+
+// blockSpan is a range of blocks, both ends included.
+type blockSpan struct {
+	from uint64
+	to   uint64
+}
+
+func (span blockSpan) String() string {
+	return fmt.Sprintf("%d to %d", span.from, span.to)
+}
+
+// Backfill scans the blocks from fromBlock to toBlock again for the events of the listener, splitting them
+// into ranges of MaxBlockRange blocks scanned by parallel workers. Events that are already indexed are skipped
+// by the handlers, so that backfilling indexed blocks is harmless. The cursor and the block hashes of the
+// listener are left untouched, which lets a backfill run alongside the live listener.
+func (listener *ContractEventListener) Backfill(ctx context.Context, fromBlock, toBlock uint64, workers int) error {
+	if fromBlock > toBlock {
+		return fmt.Errorf("invalid block range: %d is after %d", fromBlock, toBlock)
+	}
+	if workers < 1 {
+		workers = DefaultBackfillWorkers
+	}
+	spanSize := listener.MaxBlockRange
+	if spanSize == 0 {
+		spanSize = DefaultMaxBlockRange
+	}
+
+	log.LG.Infof("Backfilling blocks %d to %d for listener %s with %d workers", fromBlock, toBlock, listener.Key, workers)
+	startedAt := time.Now()
+
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		failedSpans []blockSpan
+	)
+	spans := make(chan blockSpan)
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for span := range spans {
+				if err := listener.backfillSpan(ctx, span); err != nil {
+					log.LG.Errorf("Failed to backfill blocks %s: %v", span, err)
+					mu.Lock()
+					failedSpans = append(failedSpans, span)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+schedule:
+	for spanStart := fromBlock; spanStart <= toBlock; spanStart += spanSize {
+		span := blockSpan{from: spanStart, to: spanStart + spanSize - 1}
+		if span.to > toBlock || span.to < span.from {
+			span.to = toBlock
+		}
+
+		select {
+		case spans <- span:
+		case <-ctx.Done():
+			break schedule
+		}
+		if span.to == toBlock {
+			break
+		}
+	}
+	close(spans)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return fmt.Errorf("backfill interrupted: %w", ctx.Err())
+	}
+	if len(failedSpans) > 0 {
+		return fmt.Errorf("failed to backfill %d block ranges: %v", len(failedSpans), failedSpans)
+	}
+
+	log.LG.Infof("Backfilled blocks %d to %d for listener %s in %s", fromBlock, toBlock, listener.Key, time.Since(startedAt).Round(time.Second))
+	return nil
+}
+
+// backfillSpan scans a block range with retries, halving it when the provider rejects it as too large.
+func (listener *ContractEventListener) backfillSpan(ctx context.Context, span blockSpan) error {
+	var err error
+	for retries := 0; retries < MaxRetries; retries++ {
+		if retries > 0 && !sleepContext(ctx, RetryDelay) {
+			return ctx.Err()
+		}
+
+		err = listener.scanSpan(ctx, span)
+		if err == nil {
+			return nil
+		}
+		if isBlockRangeError(err) && span.to > span.from {
+			middle := span.from + (span.to-span.from)/2
+			if err := listener.backfillSpan(ctx, blockSpan{from: span.from, to: middle}); err != nil {
+				return err
+			}
+			return listener.backfillSpan(ctx, blockSpan{from: middle + 1, to: span.to})
+		}
+		log.LG.Warnf("Failed to backfill blocks %s: %v. Retrying...", span, err)
+	}
+	return err
+}
+
+// scanSpan polls the logs of a block range and processes them in a single transaction. The processed events are
+// published to the transactional sinks within the transaction, and to the other sinks once it has been committed.
+func (listener *ContractEventListener) scanSpan(ctx context.Context, span blockSpan) error {
+	logs, err := pollForLogsFromBlock(ctx, listener.ETHClient, listener.ContractAddress, listener.EventIDs, span.from, span.to)
+	if err != nil {
+		return err
+	}
+
+	var processedEvents []Event
+	err = listener.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		processedEvents, err = listener.processLogs(ctx, logs, listener.parseAndProcessLog)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, processedEvent := range processedEvents {
+		for _, sink := range listener.Sinks {
+			if !sink.Transactional() {
+				publishWithRetries(ctx, sink, processedEvent)
+			}
+		}
+	}
+
+	log.LG.Infof("Backfilled blocks %s for listener %s: %d logs, %d new events", span, listener.Key, len(logs), len(processedEvents))
+	return nil
+}
@@ -2,48 +2,97 @@ package blockchain
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
 	"github.com/genefriendway/onchain-handler/internal/utils/log"
 )
 
 const (
 	DefaultEventChannelBufferSize = 100             // Buffer size for event channel
 	DefaultBlockOffset            = 10              // Default block offset if last processed block is missing
-	MaxBlockRange                 = 2048            // Maximum number of blocks to query at once
 	MaxRetries                    = 3               // Maximum number of retries when polling fails
 	RetryDelay                    = 3 * time.Second // Delay between retries
+	BlockHashRetention            = 256             // Number of recent block hashes kept for reorg detection
 )
 
+// ErrUnprocessableLog is wrapped by parse functions when a log cannot be decoded or is invalid.
+// Such logs are skipped, while any other error aborts and retries the whole block chunk.
+var ErrUnprocessableLog = errors.New("unprocessable log")
+
+// ErrUnhandledEvent is wrapped by parse functions when a log belongs to an event the listener does not index.
+// Such logs are skipped and only reported in the unhandled event log.
+var ErrUnhandledEvent = errors.New("unhandled event")
+
+// ParseAndProcessFunc decodes a log and persists what it describes, using ctx for all repository calls
+// so that they take part in the chunk's transaction. It returns the event to publish to the sinks.
+type ParseAndProcessFunc func(ctx context.Context, vLog types.Log) (Event, error)
+
+// RollbackFunc reverts everything a listener indexed from the given block number onwards.
+type RollbackFunc func(ctx context.Context, fromBlock uint64) error
+
 // BaseEventListener represents the shared behavior of any blockchain event listener.
 type BaseEventListener struct {
-	ETHClient       *ethclient.Client
-	ContractAddress common.Address
-	EventChan       chan interface{}
-	ParsedABI       abi.ABI
-	LastBlockRepo   interfaces.BlockStateRepository
-	CurrentBlock    uint64
+	ETHClient         EthClient
+	Key               model.ListenerKey // Identity of the listener, used to keep its own block cursor
+	ContractAddress   common.Address
+	EventIDs          []common.Hash // Signatures of the indexed events, used to filter the logs
+	EventChan         chan Event
+	ParsedABI         abi.ABI
+	LastBlockRepo     interfaces.BlockStateRepository
+	UnitOfWork        interfaces.UnitOfWork
+	CurrentBlock      uint64
+	ConfirmationDepth uint64       // Number of blocks that must be mined on top of a block before it is processed
+	Headers           *HeaderCache // Headers of the blocks the logs were included in, e.g. for their timestamps
+	Sinks             []EventSink  // Receivers of the processed events
+	SubscribeLogs     bool         // Whether to be woken up by a log subscription instead of polling, which requires a WebSocket client
+	MinBlockRange     uint64       // Smallest number of blocks queried at once, DefaultMinBlockRange when zero
+	MaxBlockRange     uint64       // Largest number of blocks queried at once, DefaultMaxBlockRange when zero
+	state             listenerState
 }
 
 // NewBaseEventListener initializes a base listener.
 func NewBaseEventListener(
-	client *ethclient.Client,
+	client EthClient,
+	chainID uint64,
 	contractAddr string,
 	parsedABI abi.ABI,
+	eventNames []string,
 	lastBlockRepo interfaces.BlockStateRepository,
+	unitOfWork interfaces.UnitOfWork,
 	startBlockListener *uint64,
+	confirmationDepth uint64,
 ) *BaseEventListener {
-	eventChan := make(chan interface{}, DefaultEventChannelBufferSize)
+	eventChan := make(chan Event, DefaultEventChannelBufferSize)
+	contractAddress := common.HexToAddress(contractAddr)
+
+	var eventIDs []common.Hash
+	for _, eventName := range eventNames {
+		event, ok := parsedABI.Events[eventName]
+		if !ok {
+			log.LG.Warnf("Event %s is not declared in the ABI of %s, its logs will not be polled", eventName, contractAddress.Hex())
+			continue
+		}
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	key := model.ListenerKey{
+		ChainID:         chainID,
+		ContractAddress: contractAddress.Hex(),
+		EventNames:      eventNames,
+	}
 
-	// Fetch the last processed block from the repository
-	lastBlock, err := lastBlockRepo.GetLastProcessedBlock(context.Background())
+	// Fetch the last processed block of this listener from the repository
+	lastBlock, err := lastBlockRepo.GetLastProcessedBlock(context.Background(), key)
 	if err != nil || lastBlock == 0 {
 		log.LG.Warnf("Failed to get last processed block or it was zero: %v", err)
 	}
@@ -58,25 +107,51 @@ func NewBaseEventListener(
 	}
 
 	return &BaseEventListener{
-		ETHClient:       client,
-		ContractAddress: common.HexToAddress(contractAddr),
-		EventChan:       eventChan,
-		ParsedABI:       parsedABI,
-		LastBlockRepo:   lastBlockRepo,
-		CurrentBlock:    currentBlock, // Store the final determined current block
+		ETHClient:         client,
+		Key:               key,
+		ContractAddress:   contractAddress,
+		EventIDs:          eventIDs,
+		EventChan:         eventChan,
+		ParsedABI:         parsedABI,
+		LastBlockRepo:     lastBlockRepo,
+		UnitOfWork:        unitOfWork,
+		CurrentBlock:      currentBlock, // Store the final determined current block
+		ConfirmationDepth: confirmationDepth,
+		Headers:           NewHeaderCache(client, DefaultHeaderCacheSize),
+		state: listenerState{
+			currentBlock: currentBlock,
+			controlChan:  make(chan struct{}, 1),
+		},
 	}
 }
 
 // RunListener starts the listener and processes incoming events.
-func (listener *BaseEventListener) RunListener(ctx context.Context, parseAndProcessFunc func(types.Log) (interface{}, error)) error {
+func (listener *BaseEventListener) RunListener(
+	ctx context.Context,
+	parseAndProcessFunc ParseAndProcessFunc,
+	rollbackFunc RollbackFunc,
+) error {
+	var subscription *logSubscription
+	if listener.SubscribeLogs {
+		subscription = newLogSubscription(listener.ETHClient, listener.ContractAddress, listener.EventIDs, listener.ConfirmationDepth)
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(2) // Two goroutines: listen and processEvents
 
 	go func() {
 		defer wg.Done()
-		listener.listen(ctx, parseAndProcessFunc)
+		listener.listen(ctx, subscription, parseAndProcessFunc, rollbackFunc)
 	}()
 
+	if subscription != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			subscription.run(ctx)
+		}()
+	}
+
 	go func() {
 		defer wg.Done()
 		listener.processEvents(ctx)
@@ -93,12 +168,20 @@ func (listener *BaseEventListener) RunListener(ctx context.Context, parseAndProc
 	return nil
 }
 
-// listen polls the blockchain for logs and parses them.
-func (listener *BaseEventListener) listen(ctx context.Context, parseAndProcessFunc func(types.Log) (interface{}, error)) {
-	log.LG.Info("Starting event listener...")
+// listen polls the blockchain for logs and parses them, until the context is cancelled. With a subscription,
+// it waits for the subscription to push a log instead of polling, as long as the subscription is connected.
+func (listener *BaseEventListener) listen(
+	ctx context.Context,
+	subscription *logSubscription,
+	parseAndProcessFunc ParseAndProcessFunc,
+	rollbackFunc RollbackFunc,
+) {
+	log.LG.Infof("Starting event listener %s...", listener.Key)
+	listener.setRunning(true)
+	defer listener.setRunning(false)
 
 	// Get the last processed block from the repository, defaulting to an offset if not found.
-	lastBlock, err := listener.LastBlockRepo.GetLastProcessedBlock(ctx)
+	lastBlock, err := listener.LastBlockRepo.GetLastProcessedBlock(ctx, listener.Key)
 	if err != nil || lastBlock == 0 {
 		log.LG.Warnf("Failed to get last processed block or it was zero: %v", err)
 		latestBlock, err := getLatestBlockNumber(ctx, listener.ETHClient)
@@ -120,92 +203,405 @@ func (listener *BaseEventListener) listen(ctx context.Context, parseAndProcessFu
 	if currentBlock == 0 {
 		currentBlock = lastBlock + 1
 	}
+	listener.setCurrentBlock(currentBlock)
+
+	// Size the block ranges of the log queries to what the provider accepts.
+	blockRange := newBlockRangeSizer(listener.MinBlockRange, listener.MaxBlockRange)
+
+	// Continuously listen for new events, one block chunk at a time.
+	for ctx.Err() == nil {
+		// Apply the commands issued through the admin API between two block chunks.
+		currentBlock, err = listener.applyPendingRewind(ctx, currentBlock)
+		if err != nil {
+			log.LG.Errorf("Failed to rewind listener %s: %v", listener.Key, err)
+			listener.recordError(err)
+			sleepContext(ctx, RetryDelay)
+			continue
+		}
+		if listener.isPaused() {
+			listener.waitForControl(ctx)
+			continue
+		}
 
-	// Continuously listen for new events.
-	for {
 		// Retrieve the latest block number from the blockchain to stay up-to-date.
 		latestBlock, err := getLatestBlockNumber(ctx, listener.ETHClient)
 		if err != nil {
 			log.LG.Errorf("Failed to retrieve the latest block number from blockchain: %v", err)
-			time.Sleep(RetryDelay)
+			listener.recordError(err)
+			sleepContext(ctx, RetryDelay)
 			continue
 		}
+		listener.setChainHead(latestBlock.Uint64())
 
-		// Ensure we do not go beyond the latest block.
-		if currentBlock > latestBlock.Uint64() {
+		// Make sure the blocks processed so far are still part of the canonical chain.
+		if currentBlock > 0 {
+			ancestor, reorged, err := listener.detectReorg(ctx, currentBlock-1)
+			if err != nil {
+				log.LG.Errorf("Failed to check for chain reorganization: %v", err)
+				listener.recordError(err)
+				sleepContext(ctx, RetryDelay)
+				continue
+			}
+			if reorged {
+				log.LG.Warnf("Chain reorganization detected. Rolling back to common ancestor block %d", ancestor)
+				if err := listener.rollback(ctx, ancestor, rollbackFunc); err != nil {
+					log.LG.Errorf("Failed to roll back to block %d: %v", ancestor, err)
+					listener.recordError(err)
+					sleepContext(ctx, RetryDelay)
+					continue
+				}
+				currentBlock = ancestor + 1
+				listener.setCurrentBlock(currentBlock)
+			}
+		}
+
+		// Only process blocks that have reached the confirmation depth.
+		safeBlock := uint64(0)
+		if latestBlock.Uint64() > listener.ConfirmationDepth {
+			safeBlock = latestBlock.Uint64() - listener.ConfirmationDepth
+		}
+
+		// Ensure we do not go beyond the latest confirmed block.
+		if currentBlock > safeBlock {
 			log.LG.Debugf("No new blocks to process. Waiting for new blocks...")
-			time.Sleep(RetryDelay) // Wait before rechecking to prevent excessive polling
+			listener.waitForBlocks(ctx, subscription) // Wait before rechecking to prevent excessive polling
 			continue
 		}
 
-		log.LG.Debugf("Listening for events starting at block: %d", currentBlock)
+		// Query as many blocks at once as the provider accepts, without going beyond the latest confirmed block.
+		chunkStart := currentBlock
+		chunkEnd := blockRange.end(chunkStart, safeBlock)
+		log.LG.Debugf("Processing block chunk: %d to %d", chunkStart, chunkEnd)
+
+		var logs []types.Log
+		// Poll logs from the blockchain with retries in case of failure.
+		for retries := 0; retries < MaxRetries; retries++ {
+			logs, err = pollForLogsFromBlock(ctx, listener.ETHClient, listener.ContractAddress, listener.EventIDs, chunkStart, chunkEnd)
+			if err == nil {
+				break
+			}
+			if isBlockRangeError(err) && blockRange.shrink() {
+				// The provider rejected the range, which is no failure of the provider
+				log.LG.Infof("Block range %d to %d rejected by the provider, narrowing it: %v", chunkStart, chunkEnd, err)
+				chunkEnd = blockRange.end(chunkStart, safeBlock)
+				retries--
+				continue
+			}
+			log.LG.Warnf("Failed to poll logs from block %d to %d: %v. Retrying...", chunkStart, chunkEnd, err)
+			if !sleepContext(ctx, RetryDelay) {
+				return
+			}
+		}
+		if err != nil {
+			log.LG.Errorf("Max retries reached polling block chunk %d to %d: %v", chunkStart, chunkEnd, err)
+			listener.recordError(err)
+			continue // Retry from the same chunk on the next iteration
+		}
 
-		// Determine the end block while respecting MaxBlockRange and the latest block.
-		endBlock := currentBlock + MaxBlockRange
-		if endBlock > latestBlock.Uint64() {
-			endBlock = latestBlock.Uint64()
+		// Persist the chunk's events together with the cursor.
+		if err := listener.processChunk(ctx, chunkEnd, logs, parseAndProcessFunc); err != nil {
+			log.LG.Errorf("Failed to process block chunk %d to %d: %v", chunkStart, chunkEnd, err)
+			listener.recordError(err)
+			sleepContext(ctx, RetryDelay)
+			continue // Retry from the same chunk on the next iteration
 		}
+		blockRange.grow(len(logs))
+
+		// Update the current block for the next iteration.
+		currentBlock = chunkEnd + 1
+		listener.setCurrentBlock(currentBlock)
 
-		// Process the blocks in chunks of 10 blocks (or DefaultBlockOffset).
-		for chunkStart := currentBlock; chunkStart <= endBlock; chunkStart += DefaultBlockOffset {
-			chunkEnd := chunkStart + DefaultBlockOffset - 1
-			if chunkEnd > endBlock {
-				chunkEnd = endBlock
+		// Drop block hashes and published events that are too old to be affected by a reorg.
+		if currentBlock > BlockHashRetention {
+			if err := listener.LastBlockRepo.DeleteProcessedBlocksBefore(ctx, listener.Key, currentBlock-BlockHashRetention); err != nil {
+				log.LG.Warnf("Failed to prune processed block hashes: %v", err)
 			}
+			if err := listener.LastBlockRepo.DeletePublishedEventsBefore(ctx, listener.Key, currentBlock-BlockHashRetention); err != nil {
+				log.LG.Warnf("Failed to prune published events: %v", err)
+			}
+		}
+	}
+}
 
-			log.LG.Debugf("Processing block chunk: %d to %d", chunkStart, chunkEnd)
+// waitForBlocks waits before checking the chain for new blocks again: until the subscription pushes a log
+// while it is connected, for RetryDelay otherwise. A command issued through the admin API ends the wait.
+func (listener *BaseEventListener) waitForBlocks(ctx context.Context, subscription *logSubscription) {
+	var wakeChan <-chan struct{}
+	delay := RetryDelay
+	if subscription != nil && subscription.connected.Load() {
+		wakeChan = subscription.wakeChan
+		delay = SubscriptionIdleTimeout
+	}
 
-			var logs []types.Log
-			// Poll logs from the blockchain with retries in case of failure.
-			for retries := 0; retries < MaxRetries; retries++ {
-				// Poll logs from the chunk of blocks.
-				logs, err = pollForLogsFromBlock(ctx, listener.ETHClient, listener.ContractAddress, chunkStart, chunkEnd)
-				if err != nil {
-					log.LG.Warnf("Failed to poll logs from block %d to %d: %v. Retrying...", chunkStart, chunkEnd, err)
-					time.Sleep(RetryDelay)
-					continue
-				}
-				break
+	select {
+	case <-ctx.Done():
+	case <-wakeChan:
+	case <-listener.state.controlChan:
+	case <-time.After(delay):
+	}
+}
+
+// waitForControl waits for a command issued through the admin API, e.g. while the listener is paused.
+func (listener *BaseEventListener) waitForControl(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-listener.state.controlChan:
+	}
+}
+
+// sleepContext waits for the given delay. It returns false when the context is cancelled first.
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// processChunk persists the events of a block chunk, the chunk's block hashes and the advanced cursor
+// in a single transaction, so that a crash can neither skip nor replay events. Processed events are
+// published to the transactional sinks within the transaction, and only sent to the channel feeding
+// the other sinks once the transaction has been committed.
+func (listener *BaseEventListener) processChunk(
+	ctx context.Context,
+	chunkEnd uint64,
+	logs []types.Log,
+	parseAndProcessFunc ParseAndProcessFunc,
+) error {
+	chunkEndHash, err := getBlockHash(ctx, listener.ETHClient, chunkEnd)
+	if err != nil {
+		return err
+	}
+
+	var processedEvents []Event
+	err = listener.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		var err error
+		processedEvents, err = listener.processLogs(ctx, logs, parseAndProcessFunc)
+		if err != nil {
+			return err
+		}
+
+		// Keep the hashes of the processed blocks so that a later reorg can be detected.
+		var processedBlocks []model.ProcessedBlock
+		for _, logEntry := range logs {
+			processedBlocks = append(processedBlocks, model.ProcessedBlock{
+				BlockNumber: logEntry.BlockNumber,
+				BlockHash:   logEntry.BlockHash.Hex(),
+			})
+		}
+		processedBlocks = append(processedBlocks, model.ProcessedBlock{
+			BlockNumber: chunkEnd,
+			BlockHash:   chunkEndHash.Hex(),
+		})
+		if err := listener.LastBlockRepo.SaveProcessedBlocks(ctx, listener.Key, processedBlocks); err != nil {
+			return fmt.Errorf("failed to save processed block hashes: %w", err)
+		}
+
+		if err := listener.LastBlockRepo.UpdateLastProcessedBlock(ctx, listener.Key, chunkEnd); err != nil {
+			return fmt.Errorf("failed to update last processed block: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Send the processed events to the channel.
+	for _, processedEvent := range processedEvents {
+		select {
+		case listener.EventChan <- processedEvent:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// processLogs parses and persists the logs, and publishes the processed events to the transactional sinks.
+// It must run within a unit of work. Logs that are unhandled or unprocessable are skipped, while any other
+// error aborts the processing.
+func (listener *BaseEventListener) processLogs(ctx context.Context, logs []types.Log, parseAndProcessFunc ParseAndProcessFunc) ([]Event, error) {
+	var processedEvents []Event
+	var publishedEvents []model.PublishedEvent
+	for _, logEntry := range logs {
+		processedEvent, err := parseAndProcessFunc(ctx, logEntry)
+		if err != nil {
+			if errors.Is(err, ErrUnhandledEvent) {
+				logUnhandledEvent(listener.Key, logEntry)
+				continue
 			}
-			if err != nil {
-				log.LG.Errorf("Max retries reached. Skipping block chunk %d to %d due to error: %v", chunkStart, chunkEnd, err)
-				break // Exit the loop if we cannot fetch logs
+			if errors.Is(err, ErrUnprocessableLog) {
+				log.LG.Errorf("Skipping log entry: %v", err)
+				continue
 			}
+			return nil, fmt.Errorf("failed to process log entry: %w", err)
+		}
+		if processedEvent == nil {
+			continue
+		}
+		processedEvents = append(processedEvents, processedEvent)
 
-			// Process the retrieved logs.
-			for _, logEntry := range logs {
-				processedEvent, err := parseAndProcessFunc(logEntry)
-				if err != nil {
-					log.LG.Errorf("Failed to process log entry: %v", err)
-					continue
-				}
+		// Keep the published event so that it can be retracted if a reorg replaces its block.
+		payload, err := json.Marshal(processedEvent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s event: %w", processedEvent.EventName(), err)
+		}
+		publishedEvents = append(publishedEvents, model.PublishedEvent{
+			EventName:   processedEvent.EventName(),
+			EventID:     processedEvent.EventID(),
+			BlockNumber: logEntry.BlockNumber,
+			Payload:     string(payload),
+		})
+
+		for _, sink := range listener.Sinks {
+			if !sink.Transactional() {
+				continue
+			}
+			if err := sink.Publish(ctx, processedEvent); err != nil {
+				return nil, fmt.Errorf("failed to publish %s event to the %s sink: %w", processedEvent.EventName(), sink.Name(), err)
+			}
+		}
+	}
 
-				// Update the last processed block in the repository.
-				if err := listener.LastBlockRepo.UpdateLastProcessedBlock(ctx, currentBlock); err != nil {
-					log.LG.Errorf("Failed to update last processed block in repository: %v", err)
-				}
+	if err := listener.LastBlockRepo.SavePublishedEvents(ctx, listener.Key, publishedEvents); err != nil {
+		return nil, fmt.Errorf("failed to save published events: %w", err)
+	}
+	return processedEvents, nil
+}
 
-				// Send the processed event to the channel.
-				listener.EventChan <- processedEvent
+// detectReorg checks whether the last processed block is still part of the canonical chain.
+// If it is not, it walks back through the stored block hashes and returns the most recent block
+// that still matches the chain, which is the common ancestor to resume from.
+func (listener *BaseEventListener) detectReorg(ctx context.Context, lastProcessedBlock uint64) (uint64, bool, error) {
+	processedBlocks, err := listener.LastBlockRepo.GetProcessedBlocks(ctx, listener.Key)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get processed block hashes: %w", err)
+	}
+
+	reorged := false
+	for _, block := range processedBlocks {
+		// Hashes above the cursor belong to blocks that will be processed again anyway.
+		if block.BlockNumber > lastProcessedBlock {
+			continue
+		}
+
+		canonicalHash, err := getBlockHash(ctx, listener.ETHClient, block.BlockNumber)
+		if err != nil {
+			return 0, false, err
+		}
+		if canonicalHash.Hex() == block.BlockHash {
+			return block.BlockNumber, reorged, nil
+		}
+
+		log.LG.Warnf("Block %d was replaced: stored hash %s, canonical hash %s", block.BlockNumber, block.BlockHash, canonicalHash.Hex())
+		reorged = true
+	}
+
+	if !reorged {
+		return 0, false, nil
+	}
+
+	// None of the retained hashes match, so rewind to just before the oldest one we know about.
+	oldestBlock := processedBlocks[len(processedBlocks)-1].BlockNumber
+	ancestor := uint64(0)
+	if oldestBlock > 0 {
+		ancestor = oldestBlock - 1
+	}
+	log.LG.Errorf("Chain reorganization is deeper than the %d retained block hashes. Rewinding to block %d", BlockHashRetention, ancestor)
+	return ancestor, true, nil
+}
+
+// rollback reverts everything indexed after the common ancestor block and moves the cursor back to it, all in a
+// single transaction. The events published from the replaced blocks are retracted from the transactional sinks
+// within the transaction, and only sent to the channel feeding the other sinks once it has been committed.
+func (listener *BaseEventListener) rollback(ctx context.Context, ancestor uint64, rollbackFunc RollbackFunc) error {
+	var retractedEvents []Event
+	err := listener.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if rollbackFunc != nil {
+			if err := rollbackFunc(ctx, ancestor+1); err != nil {
+				return fmt.Errorf("failed to roll back indexed events: %w", err)
 			}
+		}
 
-			// Update the current block for the next iteration.
-			currentBlock = chunkEnd + 1
+		var err error
+		if retractedEvents, err = listener.retractEvents(ctx, ancestor+1); err != nil {
+			return err
 		}
 
-		// Update the last processed block in the repository.
-		if err := listener.LastBlockRepo.UpdateLastProcessedBlock(ctx, currentBlock); err != nil {
-			log.LG.Errorf("Failed to update last processed block in repository: %v", err)
+		if err := listener.LastBlockRepo.DeleteProcessedBlocksFrom(ctx, listener.Key, ancestor+1); err != nil {
+			return fmt.Errorf("failed to delete replaced block hashes: %w", err)
 		}
+
+		if err := listener.LastBlockRepo.UpdateLastProcessedBlock(ctx, listener.Key, ancestor); err != nil {
+			return fmt.Errorf("failed to update last processed block: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+
+	for _, retractedEvent := range retractedEvents {
+		select {
+		case listener.EventChan <- retractedEvent:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
 }
 
-// processEvents handles events from the EventChan.
+// retractEvents retracts the events published from the given block onwards from the transactional sinks, and
+// returns them for the other sinks. It must run within a unit of work.
+func (listener *BaseEventListener) retractEvents(ctx context.Context, fromBlock uint64) ([]Event, error) {
+	publishedEvents, err := listener.LastBlockRepo.GetPublishedEventsFrom(ctx, listener.Key, fromBlock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get published events: %w", err)
+	}
+
+	retractedEvents := make([]Event, 0, len(publishedEvents))
+	for _, publishedEvent := range publishedEvents {
+		retractedEvent := &RetractedEvent{
+			Name:    publishedEvent.EventName,
+			ID:      publishedEvent.EventID,
+			Payload: json.RawMessage(publishedEvent.Payload),
+		}
+		retractedEvents = append(retractedEvents, retractedEvent)
+
+		for _, sink := range listener.Sinks {
+			if !sink.Transactional() {
+				continue
+			}
+			if err := sink.Retract(ctx, retractedEvent); err != nil {
+				return nil, fmt.Errorf("failed to retract %s event from the %s sink: %w", retractedEvent.EventName(), sink.Name(), err)
+			}
+		}
+	}
+
+	if err := listener.LastBlockRepo.DeletePublishedEventsFrom(ctx, listener.Key, fromBlock); err != nil {
+		return nil, fmt.Errorf("failed to delete retracted events: %w", err)
+	}
+	if len(retractedEvents) > 0 {
+		log.LG.Warnf("Retracted %d events from block %d onwards published by listener %s", len(retractedEvents), fromBlock, listener.Key)
+	}
+	return retractedEvents, nil
+}
+
+// processEvents publishes the events from the EventChan to the sinks that are not transactional, and retracts the
+// retracted ones from them.
 func (listener *BaseEventListener) processEvents(ctx context.Context) {
 	for {
 		select {
 		case event := <-listener.EventChan:
 			log.LG.Debugf("Received event: %+v", event)
+			for _, sink := range listener.Sinks {
+				if sink.Transactional() {
+					continue
+				}
+				publishWithRetries(ctx, sink, event)
+			}
 
 		case <-ctx.Done():
 			log.LG.Info("Stopping event processing...")
@@ -213,3 +609,20 @@ func (listener *BaseEventListener) processEvents(ctx context.Context) {
 		}
 	}
 }
+
+// logUnhandledEvent reports a log of an event the listener does not index, which the topic filter should
+// have left out, e.g. because the node ignores it or the ABI declares another event with the same name.
+func logUnhandledEvent(key model.ListenerKey, vLog types.Log) {
+	topic := "none"
+	if len(vLog.Topics) > 0 {
+		topic = vLog.Topics[0].Hex()
+	}
+	log.LG.Instance.Info().Timestamp().
+		Str("log", "unhandled").
+		Str("listener", key.String()).
+		Str("topic", topic).
+		Str("tx_hash", vLog.TxHash.Hex()).
+		Uint64("block_number", vLog.BlockNumber).
+		Uint("log_index", vLog.Index).
+		Msg("Unhandled event")
+}
@@ -0,0 +1,107 @@
+package blockchain
+
+import (
+	"strings"
+)
+
+const (
+	DefaultMinBlockRange     = 1    // Smallest number of blocks queried at once, the range is not halved below it
+	DefaultMaxBlockRange     = 2048 // Largest number of blocks queried at once
+	DefaultInitialBlockRange = 10   // Number of blocks queried at once when a listener starts
+	SmallResponseLogs        = 500  // Responses with fewer logs than this grow the range for the next query
+)
+
+// blockRangeErrors are fragments of the errors returned by the providers when a log query spans too many
+// blocks or matches too many logs.
+var blockRangeErrors = []string{
+	"too many results",
+	"query returned more than",
+	"block range",
+	"range too large",
+	"range is too large",
+	"exceed maximum block range",
+	"response size exceeded",
+	"log response size",
+	"limit the query",
+}
+
+// isBlockRangeError reports whether a log query failed because its block range is too large for the provider.
+func isBlockRangeError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	for _, fragment := range blockRangeErrors {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockRangeSizer adapts the number of blocks queried at once to the density of the logs: the range
+// doubles while the responses are small and is halved when the provider rejects a query as too large.
+type blockRangeSizer struct {
+	size uint64
+	min  uint64
+	max  uint64
+}
+
+// newBlockRangeSizer initializes a sizer within the given limits, the defaults being used for zero limits.
+func newBlockRangeSizer(minRange, maxRange uint64) *blockRangeSizer {
+	if minRange == 0 {
+		minRange = DefaultMinBlockRange
+	}
+	if maxRange == 0 {
+		maxRange = DefaultMaxBlockRange
+	}
+	if maxRange < minRange {
+		maxRange = minRange
+	}
+
+	size := uint64(DefaultInitialBlockRange)
+	if size < minRange {
+		size = minRange
+	}
+	if size > maxRange {
+		size = maxRange
+	}
+
+	return &blockRangeSizer{
+		size: size,
+		min:  minRange,
+		max:  maxRange,
+	}
+}
+
+// end returns the last block of the range starting at the given block, without going beyond lastBlock.
+func (sizer *blockRangeSizer) end(start, lastBlock uint64) uint64 {
+	end := start + sizer.size - 1
+	if end > lastBlock {
+		end = lastBlock
+	}
+	return end
+}
+
+// grow doubles the range after a response with few logs.
+func (sizer *blockRangeSizer) grow(logCount int) {
+	if logCount >= SmallResponseLogs || sizer.size >= sizer.max {
+		return
+	}
+	sizer.size *= 2
+	if sizer.size > sizer.max {
+		sizer.size = sizer.max
+	}
+}
+
+// shrink halves the range after the provider rejected it. It returns false when the range is already the smallest allowed.
+func (sizer *blockRangeSizer) shrink() bool {
+	if sizer.size <= sizer.min {
+		return false
+	}
+	sizer.size /= 2
+	if sizer.size < sizer.min {
+		sizer.size = sizer.min
+	}
+	return true
+}
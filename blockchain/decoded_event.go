@@ -0,0 +1,131 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DecodedEvent is a log decoded with the ABI of the event it was emitted for.
+type DecodedEvent struct {
+	Name string                 // Name of the event in the ABI
+	Log  types.Log              // Raw log
+	Args map[string]interface{} // Indexed and non-indexed arguments by their ABI name
+
+	abiEvent abi.Event
+	headers  *HeaderCache
+}
+
+// decodeEvent decodes the indexed arguments of the log from its topics and the other arguments from its data.
+// Indexed arguments of a dynamic type, such as strings, are only available as the hash of their value.
+func decodeEvent(abiEvent abi.Event, vLog types.Log, headers *HeaderCache) (*DecodedEvent, error) {
+	if len(vLog.Topics) == 0 || vLog.Topics[0] != abiEvent.ID {
+		return nil, fmt.Errorf("log is not a %s event", abiEvent.Name)
+	}
+
+	// A log of another event with the same signature but other indexed arguments has another number of topics
+	indexed := indexedArguments(abiEvent)
+	if len(vLog.Topics)-1 != len(indexed) {
+		return nil, fmt.Errorf("%s has %d indexed arguments, but the log has %d topics", abiEvent.Name, len(indexed), len(vLog.Topics)-1)
+	}
+
+	args := make(map[string]interface{})
+	if err := abiEvent.Inputs.NonIndexed().UnpackIntoMap(args, vLog.Data); err != nil {
+		return nil, fmt.Errorf("failed to unpack data: %w", err)
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, vLog.Topics[1:]); err != nil {
+		return nil, fmt.Errorf("failed to parse topics: %w", err)
+	}
+
+	return &DecodedEvent{
+		Name:     abiEvent.Name,
+		Log:      vLog,
+		Args:     args,
+		abiEvent: abiEvent,
+		headers:  headers,
+	}, nil
+}
+
+func (e *DecodedEvent) EventName() string {
+	return e.Name
+}
+
+// EventID identifies the event by its transaction and position in the block.
+func (e *DecodedEvent) EventID() string {
+	return fmt.Sprintf("%s:%d", e.Log.TxHash.Hex(), e.Log.Index)
+}
+
+// Decode decodes the arguments into out, a pointer to a struct with a field per argument named after the
+// camel-cased argument name, e.g. OrderId for orderId.
+func (e *DecodedEvent) Decode(out interface{}) error {
+	nonIndexed := e.abiEvent.Inputs.NonIndexed()
+	values, err := nonIndexed.Unpack(e.Log.Data)
+	if err != nil {
+		return fmt.Errorf("failed to unpack data of %s: %w", e.Name, err)
+	}
+	if len(values) > 0 {
+		if err := nonIndexed.Copy(out, values); err != nil {
+			return fmt.Errorf("failed to copy data of %s: %w", e.Name, err)
+		}
+	}
+	if err := abi.ParseTopics(out, indexedArguments(e.abiEvent), e.Log.Topics[1:]); err != nil {
+		return fmt.Errorf("failed to parse topics of %s: %w", e.Name, err)
+	}
+	return nil
+}
+
+// BlockTime returns the timestamp of the block that included the event.
+func (e *DecodedEvent) BlockTime(ctx context.Context) (time.Time, error) {
+	return e.headers.BlockTime(ctx, e.Log.BlockHash)
+}
+
+// MarshalJSON encodes the event with its position in the chain. Integers are encoded as decimal strings,
+// as they may exceed the precision of JSON numbers.
+func (e *DecodedEvent) MarshalJSON() ([]byte, error) {
+	args := make(map[string]interface{}, len(e.Args))
+	for name, value := range e.Args {
+		switch v := value.(type) {
+		case *big.Int:
+			args[name] = v.String()
+		case [32]byte:
+			args[name] = common.Hash(v).Hex()
+		default:
+			args[name] = v
+		}
+	}
+
+	return json.Marshal(struct {
+		Name            string                 `json:"name"`
+		ContractAddress string                 `json:"contract_address"`
+		TransactionHash string                 `json:"transaction_hash"`
+		BlockNumber     uint64                 `json:"block_number"`
+		BlockHash       string                 `json:"block_hash"`
+		LogIndex        uint                   `json:"log_index"`
+		Args            map[string]interface{} `json:"args"`
+	}{
+		Name:            e.Name,
+		ContractAddress: e.Log.Address.Hex(),
+		TransactionHash: e.Log.TxHash.Hex(),
+		BlockNumber:     e.Log.BlockNumber,
+		BlockHash:       e.Log.BlockHash.Hex(),
+		LogIndex:        e.Log.Index,
+		Args:            args,
+	})
+}
+
+// indexedArguments returns the indexed arguments of an event, in the order of its topics.
+func indexedArguments(abiEvent abi.Event) abi.Arguments {
+	var indexed abi.Arguments
+	for _, input := range abiEvent.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	return indexed
+}
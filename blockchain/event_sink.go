@@ -0,0 +1,104 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// Event is implemented by the events processed by the listeners.
+type Event interface {
+	EventName() string // Name of the contract event, e.g. MembershipPurchased
+	EventID() string   // Identifies the event, so that consumers can ignore duplicates
+}
+
+// RetractedEvent is an event published earlier from a block that a chain reorg replaced. It is encoded as the
+// payload the event was published with.
+type RetractedEvent struct {
+	Name    string
+	ID      string
+	Payload json.RawMessage
+}
+
+func (e *RetractedEvent) EventName() string {
+	return e.Name
+}
+
+func (e *RetractedEvent) EventID() string {
+	return e.ID
+}
+
+func (e *RetractedEvent) MarshalJSON() ([]byte, error) {
+	return e.Payload, nil
+}
+
+// EventSink receives the events processed by a listener.
+type EventSink interface {
+	Name() string
+	// Transactional reports whether the sink writes to the database. Transactional sinks are published to in the
+	// transaction indexing the event, with its context, so that they receive each indexed event exactly once.
+	// Other sinks are published to once the transaction is committed, and may miss events on a crash.
+	Transactional() bool
+	Publish(ctx context.Context, event Event) error
+	// Retract tells the consumers that an event published earlier was removed from the chain by a reorg.
+	// Transactional sinks are retracted from in the transaction rolling the listener back.
+	Retract(ctx context.Context, event *RetractedEvent) error
+}
+
+// publishWithRetries publishes an event to a sink that is not transactional, or retracts it from the sink if it is a
+// RetractedEvent, retrying a few times before giving up.
+func publishWithRetries(ctx context.Context, sink EventSink, event Event) {
+	publish := sink.Publish
+	if retractedEvent, ok := event.(*RetractedEvent); ok {
+		publish = func(ctx context.Context, _ Event) error {
+			return sink.Retract(ctx, retractedEvent)
+		}
+	}
+
+	var err error
+	for retries := 0; retries < MaxRetries; retries++ {
+		if err = publish(ctx, event); err == nil {
+			return
+		}
+		log.LG.Warnf("Failed to publish %s event %s to the %s sink: %v. Retrying...", event.EventName(), event.EventID(), sink.Name(), err)
+
+		select {
+		case <-time.After(RetryDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+	log.LG.Errorf("Max retries reached. Dropping %s event %s for the %s sink: %v", event.EventName(), event.EventID(), sink.Name(), err)
+}
+
+// WebhookEventSink queues the events for delivery to the webhooks subscribed to them.
+type WebhookEventSink struct {
+	Publisher interfaces.WebhookPublisher
+}
+
+func NewWebhookEventSink(publisher interfaces.WebhookPublisher) *WebhookEventSink {
+	return &WebhookEventSink{
+		Publisher: publisher,
+	}
+}
+
+func (sink *WebhookEventSink) Name() string {
+	return "webhook"
+}
+
+// Transactional is true, as the deliveries are queued in the database and sent by the webhook workers.
+func (sink *WebhookEventSink) Transactional() bool {
+	return true
+}
+
+func (sink *WebhookEventSink) Publish(ctx context.Context, event Event) error {
+	return sink.Publisher.Publish(ctx, event.EventName(), event.EventID(), event)
+}
+
+// Retract queues a delivery of the event with removed set to the subscribers that may have received it.
+func (sink *WebhookEventSink) Retract(ctx context.Context, event *RetractedEvent) error {
+	return sink.Publisher.Retract(ctx, event.EventName(), event.EventID(), event)
+}
@@ -0,0 +1,281 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+
+	"github.com/genefriendway/onchain-handler/conf"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// Fee strategies that can be selected with FEE_STRATEGY
+const (
+	FeeStrategyAuto    = "auto"    // EIP-1559 when the latest block has a base fee, legacy otherwise
+	FeeStrategyEIP1559 = "eip1559" // Dynamic fee transactions
+	FeeStrategyLegacy  = "legacy"  // Legacy gas price transactions
+)
+
+const (
+	DefaultGasPriceMultiplier = 1.0 // Multiplier applied to the suggested legacy gas price
+	DefaultBaseFeeMultiplier  = 2.0 // Multiplier applied to the next base fee to absorb base fee increases
+	DefaultTipMultiplier      = 1.0 // Multiplier applied to the suggested priority fee
+)
+
+// Fees holds the fee fields of a transaction, either GasPrice for legacy transactions
+// or GasFeeCap and GasTipCap for EIP-1559 transactions.
+type Fees struct {
+	GasPrice  *big.Int
+	GasFeeCap *big.Int
+	GasTipCap *big.Int
+}
+
+// IsDynamic reports whether the fees are for an EIP-1559 transaction.
+func (fees *Fees) IsDynamic() bool {
+	return fees.GasFeeCap != nil
+}
+
+// Apply sets the fees on the transactor.
+func (fees *Fees) Apply(auth *bind.TransactOpts) {
+	auth.GasPrice = fees.GasPrice
+	auth.GasFeeCap = fees.GasFeeCap
+	auth.GasTipCap = fees.GasTipCap
+}
+
+// TxData builds the unsigned transaction matching the fee type.
+func (fees *Fees) TxData(nonce uint64, to *common.Address, value *big.Int, gas uint64, data []byte) types.TxData {
+	if fees.IsDynamic() {
+		return &types.DynamicFeeTx{
+			Nonce:     nonce,
+			To:        to,
+			Value:     value,
+			Gas:       gas,
+			GasFeeCap: fees.GasFeeCap,
+			GasTipCap: fees.GasTipCap,
+			Data:      data,
+		}
+	}
+	return &types.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Value:    value,
+		Gas:      gas,
+		GasPrice: fees.GasPrice,
+		Data:     data,
+	}
+}
+
+// FeesOf returns the fees a transaction was sent with.
+func FeesOf(tx *types.Transaction) *Fees {
+	if tx.Type() == types.DynamicFeeTxType {
+		return &Fees{GasFeeCap: tx.GasFeeCap(), GasTipCap: tx.GasTipCap()}
+	}
+	return &Fees{GasPrice: tx.GasPrice()}
+}
+
+// FeeStrategy decides the fees of outgoing transactions.
+type FeeStrategy interface {
+	// SuggestFees returns the fees to use for a new transaction.
+	SuggestFees(ctx context.Context) (*Fees, error)
+	// BumpFees returns the fees for a transaction replacing one sent with the current fees,
+	// raised by at least bumpPercent. It fails when the bumped fees exceed the configured caps.
+	BumpFees(ctx context.Context, current *Fees, bumpPercent uint64) (*Fees, error)
+}
+
+// NewFeeStrategy selects the fee strategy configured for the chain. With the "auto" strategy,
+// EIP-1559 is used when the chain has activated London, detected by the base fee of the latest block.
+func NewFeeStrategy(ctx context.Context, client EthClient, config conf.BlockchainConfiguration) (FeeStrategy, error) {
+	strategy := strings.ToLower(config.FeeStrategy)
+	if strategy == "" {
+		strategy = FeeStrategyAuto
+	}
+
+	if strategy == FeeStrategyAuto {
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the latest block header: %w", err)
+		}
+		strategy = FeeStrategyLegacy
+		if header.BaseFee != nil {
+			strategy = FeeStrategyEIP1559
+		}
+	}
+
+	maxGasPrice := gweiToWei(config.MaxGasPriceGwei)
+	switch strategy {
+	case FeeStrategyEIP1559:
+		log.LG.Infof("Using EIP-1559 fee strategy for chain %d", config.ChainID)
+		return &dynamicFeeStrategy{
+			client:            client,
+			baseFeeMultiplier: valueOrDefault(config.BaseFeeMultiplier, DefaultBaseFeeMultiplier),
+			tipMultiplier:     valueOrDefault(config.TipMultiplier, DefaultTipMultiplier),
+			maxFeeCap:         maxGasPrice,
+			maxTipCap:         gweiToWei(config.MaxPriorityFeeGwei),
+		}, nil
+	case FeeStrategyLegacy:
+		log.LG.Infof("Using legacy fee strategy for chain %d", config.ChainID)
+		return &legacyFeeStrategy{
+			client:      client,
+			multiplier:  valueOrDefault(config.GasPriceMultiplier, DefaultGasPriceMultiplier),
+			maxGasPrice: maxGasPrice,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown fee strategy: %s", config.FeeStrategy)
+	}
+}
+
+// legacyFeeStrategy prices transactions with the gas price suggested by the node.
+type legacyFeeStrategy struct {
+	client      EthClient
+	multiplier  float64
+	maxGasPrice *big.Int // nil for no cap
+}
+
+func (strategy *legacyFeeStrategy) SuggestFees(ctx context.Context) (*Fees, error) {
+	gasPrice, err := strategy.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+
+	gasPrice = multiply(gasPrice, strategy.multiplier)
+	if exceedsCap(gasPrice, strategy.maxGasPrice) {
+		log.LG.Warnf("Suggested gas price %s exceeds the cap of %s. Using the cap", gasPrice, strategy.maxGasPrice)
+		gasPrice = strategy.maxGasPrice
+	}
+
+	return &Fees{GasPrice: gasPrice}, nil
+}
+
+func (strategy *legacyFeeStrategy) BumpFees(ctx context.Context, current *Fees, bumpPercent uint64) (*Fees, error) {
+	suggested, err := strategy.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	currentGasPrice := current.GasPrice
+	if current.IsDynamic() {
+		currentGasPrice = current.GasFeeCap
+	}
+
+	gasPrice := maxBig(bumpPercentage(currentGasPrice, bumpPercent), suggested.GasPrice)
+	if exceedsCap(gasPrice, strategy.maxGasPrice) {
+		return nil, fmt.Errorf("bumped gas price %s exceeds the cap of %s", gasPrice, strategy.maxGasPrice)
+	}
+
+	return &Fees{GasPrice: gasPrice}, nil
+}
+
+// dynamicFeeStrategy prices EIP-1559 transactions from the suggested priority fee and the next base fee.
+type dynamicFeeStrategy struct {
+	client            EthClient
+	baseFeeMultiplier float64
+	tipMultiplier     float64
+	maxFeeCap         *big.Int // nil for no cap
+	maxTipCap         *big.Int // nil for no cap
+}
+
+func (strategy *dynamicFeeStrategy) SuggestFees(ctx context.Context) (*Fees, error) {
+	gasTipCap, err := strategy.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas tip cap: %w", err)
+	}
+	gasTipCap = multiply(gasTipCap, strategy.tipMultiplier)
+	if exceedsCap(gasTipCap, strategy.maxTipCap) {
+		gasTipCap = strategy.maxTipCap
+	}
+
+	// The fee history includes the base fee of the next block after the requested range.
+	feeHistory, err := strategy.client.FeeHistory(ctx, 1, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee history: %w", err)
+	}
+	if len(feeHistory.BaseFee) == 0 {
+		return nil, fmt.Errorf("fee history has no base fee")
+	}
+	nextBaseFee := feeHistory.BaseFee[len(feeHistory.BaseFee)-1]
+
+	gasFeeCap := new(big.Int).Add(multiply(nextBaseFee, strategy.baseFeeMultiplier), gasTipCap)
+	if exceedsCap(gasFeeCap, strategy.maxFeeCap) {
+		log.LG.Warnf("Suggested fee cap %s exceeds the cap of %s. Using the cap", gasFeeCap, strategy.maxFeeCap)
+		gasFeeCap = strategy.maxFeeCap
+	}
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasTipCap = gasFeeCap
+	}
+
+	return &Fees{GasFeeCap: gasFeeCap, GasTipCap: gasTipCap}, nil
+}
+
+func (strategy *dynamicFeeStrategy) BumpFees(ctx context.Context, current *Fees, bumpPercent uint64) (*Fees, error) {
+	suggested, err := strategy.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A legacy transaction is replaced by one whose fee cap and tip both beat its gas price.
+	currentFeeCap, currentTipCap := current.GasFeeCap, current.GasTipCap
+	if !current.IsDynamic() {
+		currentFeeCap, currentTipCap = current.GasPrice, current.GasPrice
+	}
+
+	gasFeeCap := maxBig(bumpPercentage(currentFeeCap, bumpPercent), suggested.GasFeeCap)
+	gasTipCap := maxBig(bumpPercentage(currentTipCap, bumpPercent), suggested.GasTipCap)
+	if exceedsCap(gasFeeCap, strategy.maxFeeCap) {
+		return nil, fmt.Errorf("bumped fee cap %s exceeds the cap of %s", gasFeeCap, strategy.maxFeeCap)
+	}
+	if exceedsCap(gasTipCap, strategy.maxTipCap) {
+		return nil, fmt.Errorf("bumped tip cap %s exceeds the cap of %s", gasTipCap, strategy.maxTipCap)
+	}
+	if gasTipCap.Cmp(gasFeeCap) > 0 {
+		gasFeeCap = gasTipCap
+	}
+
+	return &Fees{GasFeeCap: gasFeeCap, GasTipCap: gasTipCap}, nil
+}
+
+// multiply scales a wei amount by a floating point multiplier.
+func multiply(value *big.Int, multiplier float64) *big.Int {
+	result, _ := new(big.Float).Mul(new(big.Float).SetInt(value), big.NewFloat(multiplier)).Int(nil)
+	return result
+}
+
+// bumpPercentage raises a wei amount by the given percentage.
+func bumpPercentage(value *big.Int, percent uint64) *big.Int {
+	result := new(big.Int).Mul(value, new(big.Int).SetUint64(100+percent))
+	return result.Div(result, big.NewInt(100))
+}
+
+// maxBig returns the larger of two amounts.
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// exceedsCap reports whether value is above cap, where a nil cap means no cap.
+func exceedsCap(value, cap *big.Int) bool {
+	return cap != nil && value.Cmp(cap) > 0
+}
+
+// gweiToWei converts a configured gwei amount to wei, where 0 means not configured.
+func gweiToWei(gwei uint64) *big.Int {
+	if gwei == 0 {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(params.GWei))
+}
+
+// valueOrDefault returns the configured multiplier, or the default when it is not configured.
+func valueOrDefault(value, defaultValue float64) float64 {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
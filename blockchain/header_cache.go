@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultHeaderCacheSize is the number of block headers kept by a HeaderCache.
+const DefaultHeaderCacheSize = 1024
+
+// HeaderCache fetches block headers by hash and keeps the most recently used ones, so that the many logs
+// of a block chunk only cost one RPC call per block. Headers are keyed by hash, which stays correct across reorgs.
+type HeaderCache struct {
+	client  EthClient
+	headers *lru.Cache[common.Hash, *types.Header]
+}
+
+// NewHeaderCache initializes a header cache holding up to size headers.
+func NewHeaderCache(client EthClient, size int) *HeaderCache {
+	return &HeaderCache{
+		client:  client,
+		headers: lru.NewCache[common.Hash, *types.Header](size),
+	}
+}
+
+// HeaderByHash returns the header of the block with the given hash.
+func (cache *HeaderCache) HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error) {
+	if header, ok := cache.headers.Get(blockHash); ok {
+		return header, nil
+	}
+
+	header, err := cache.client.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the header of block %s: %w", blockHash.Hex(), err)
+	}
+	cache.headers.Add(blockHash, header)
+	return header, nil
+}
+
+// BlockTime returns the timestamp of the block with the given hash.
+func (cache *HeaderCache) BlockTime(ctx context.Context, blockHash common.Hash) (time.Time, error) {
+	header, err := cache.HeaderByHash(ctx, blockHash)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(header.Time), 0).UTC(), nil
+}
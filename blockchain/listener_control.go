@@ -0,0 +1,197 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/model"
+)
+
+// ErrInvalidRewindBlock is returned when a listener is rewound to block 0 or to a block it has not reached yet.
+var ErrInvalidRewindBlock = errors.New("invalid rewind block")
+
+// ListenerStatus is a snapshot of the progress of a listener.
+type ListenerStatus struct {
+	Key           model.ListenerKey
+	Running       bool
+	Paused        bool
+	CurrentBlock  uint64     // Next block to process
+	ChainHead     uint64     // Latest block of the chain at the last check, 0 before the first check
+	PendingRewind *uint64    // Block the listener is about to be rewound to
+	PendingReset  bool       // Whether the pending rewind also forgets the state of the listener
+	LastError     string     // Last error that held the listener back
+	LastErrorAt   *time.Time // When the last error occurred
+}
+
+// listenerState is the progress of a running listener and the commands it has yet to apply. The listen
+// loop applies the commands itself, between two block chunks, so that they never race with the indexing.
+type listenerState struct {
+	mu            sync.Mutex
+	running       bool
+	paused        bool
+	currentBlock  uint64
+	chainHead     uint64
+	pendingRewind *uint64
+	pendingReset  bool
+	lastError     string
+	lastErrorAt   *time.Time
+	controlChan   chan struct{} // Wakes the listen loop up when a command is issued
+}
+
+// Status returns the progress of the listener.
+func (listener *BaseEventListener) Status() ListenerStatus {
+	state := &listener.state
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	status := ListenerStatus{
+		Key:          listener.Key,
+		Running:      state.running,
+		Paused:       state.paused,
+		CurrentBlock: state.currentBlock,
+		ChainHead:    state.chainHead,
+		PendingReset: state.pendingReset,
+		LastError:    state.lastError,
+	}
+	if state.pendingRewind != nil {
+		pendingRewind := *state.pendingRewind
+		status.PendingRewind = &pendingRewind
+	}
+	if state.lastErrorAt != nil {
+		lastErrorAt := *state.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	return status
+}
+
+// Pause stops the listener from processing blocks once the chunk being processed is done.
+func (listener *BaseEventListener) Pause() {
+	listener.state.mu.Lock()
+	listener.state.paused = true
+	listener.state.mu.Unlock()
+	listener.notifyControl()
+}
+
+// Resume lets a paused listener process blocks again.
+func (listener *BaseEventListener) Resume() {
+	listener.state.mu.Lock()
+	listener.state.paused = false
+	listener.state.mu.Unlock()
+	listener.notifyControl()
+}
+
+// Rewind makes the listener process the blocks again from the given block, which must not be after the next
+// block to process. The rewind is applied by the listener before its next block chunk, even while paused.
+// Events indexed again are skipped by the handlers.
+func (listener *BaseEventListener) Rewind(block uint64) error {
+	state := &listener.state
+	state.mu.Lock()
+	if block == 0 || block > state.currentBlock {
+		state.mu.Unlock()
+		return fmt.Errorf("%w: block %d is not between 1 and the next block to process %d", ErrInvalidRewindBlock, block, state.currentBlock)
+	}
+	state.pendingRewind = &block
+	state.pendingReset = false
+	state.mu.Unlock()
+
+	listener.notifyControl()
+	return nil
+}
+
+// reset makes the listener forget its cursor, block hashes and published events, and process the blocks again
+// from the given start block. Like a rewind, the reset is applied by the listener before its next block chunk.
+func (listener *BaseEventListener) reset(startBlock uint64) {
+	state := &listener.state
+	state.mu.Lock()
+	state.pendingRewind = &startBlock
+	state.pendingReset = true
+	state.mu.Unlock()
+
+	listener.notifyControl()
+}
+
+func (listener *BaseEventListener) notifyControl() {
+	select {
+	case listener.state.controlChan <- struct{}{}:
+	default:
+	}
+}
+
+func (listener *BaseEventListener) setRunning(running bool) {
+	listener.state.mu.Lock()
+	defer listener.state.mu.Unlock()
+	listener.state.running = running
+}
+
+func (listener *BaseEventListener) isPaused() bool {
+	listener.state.mu.Lock()
+	defer listener.state.mu.Unlock()
+	return listener.state.paused
+}
+
+func (listener *BaseEventListener) setCurrentBlock(block uint64) {
+	listener.state.mu.Lock()
+	defer listener.state.mu.Unlock()
+	listener.state.currentBlock = block
+}
+
+func (listener *BaseEventListener) setChainHead(block uint64) {
+	listener.state.mu.Lock()
+	defer listener.state.mu.Unlock()
+	listener.state.chainHead = block
+}
+
+// recordError keeps the error as the last error of the listener.
+func (listener *BaseEventListener) recordError(err error) {
+	now := time.Now().UTC()
+	listener.state.mu.Lock()
+	defer listener.state.mu.Unlock()
+	listener.state.lastError = err.Error()
+	listener.state.lastErrorAt = &now
+}
+
+// applyPendingRewind moves the cursor back to just before the pending rewind block, if any, and drops the hashes
+// of the blocks to process again. A pending reset drops the whole state of the listener instead.
+// It returns the next block to process.
+func (listener *BaseEventListener) applyPendingRewind(ctx context.Context, currentBlock uint64) (uint64, error) {
+	listener.state.mu.Lock()
+	pendingRewind := listener.state.pendingRewind
+	pendingReset := listener.state.pendingReset
+	listener.state.mu.Unlock()
+	if pendingRewind == nil {
+		return currentBlock, nil
+	}
+	block := *pendingRewind
+
+	err := listener.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if pendingReset {
+			if err := listener.LastBlockRepo.ResetListenerState(ctx, listener.Key); err != nil {
+				return fmt.Errorf("failed to reset listener state: %w", err)
+			}
+			return nil
+		}
+		if err := listener.LastBlockRepo.DeleteProcessedBlocksFrom(ctx, listener.Key, block); err != nil {
+			return fmt.Errorf("failed to delete block hashes: %w", err)
+		}
+		if err := listener.LastBlockRepo.UpdateLastProcessedBlock(ctx, listener.Key, block-1); err != nil {
+			return fmt.Errorf("failed to update last processed block: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return currentBlock, err
+	}
+
+	listener.state.mu.Lock()
+	defer listener.state.mu.Unlock()
+	// A rewind issued in the meantime is applied on the next iteration
+	if listener.state.pendingRewind == pendingRewind {
+		listener.state.pendingRewind = nil
+		listener.state.pendingReset = false
+	}
+	listener.state.currentBlock = block
+	return block, nil
+}
@@ -0,0 +1,278 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// EventHandler persists a decoded event, using ctx for all repository calls so that they take part in the
+// chunk's transaction. It returns the event to publish to the sinks, or a nil interface to publish none.
+// Errors wrapping ErrUnprocessableLog skip the event, any other error retries the whole block chunk.
+type EventHandler func(ctx context.Context, event *DecodedEvent) (Event, error)
+
+// StartBlockFunc returns the first block a listener indexes when it has no block cursor yet.
+type StartBlockFunc func(ctx context.Context) (uint64, error)
+
+// ListenerDefinition declares a listener indexing events of a contract.
+type ListenerDefinition struct {
+	Name            string                  // Name of the listener, e.g. to select it in the backfill command
+	ABIFile         string                  // Path of the contract ABI, relative to the working directory
+	ContractAddress string                  // Address of the contract emitting the events
+	Handlers        map[string]EventHandler // Handler of each indexed event, by event name
+	Rollback        RollbackFunc            // Reverts what the handlers indexed after a chain reorg, may be nil
+	LegacyCursor    bool                    // Whether the listener takes over the cursor that predates per-listener cursors
+	StartBlock      StartBlockFunc          // First block to index, the StartBlockListener of the registry is used when nil
+}
+
+// registeredHandler is the handler of an event, keyed by the event signature.
+type registeredHandler struct {
+	event  abi.Event
+	handle EventHandler
+}
+
+// ContractEventListener indexes the events of a contract with the handlers registered for their signatures.
+type ContractEventListener struct {
+	*BaseEventListener
+	Name              string
+	handlers          map[common.Hash]registeredHandler
+	rollback          RollbackFunc
+	legacyCursor      bool
+	startBlock        *uint64        // Start block shared by the listeners of the registry
+	resolveStartBlock StartBlockFunc // Own start block of the listener
+}
+
+// parseAndProcessLog decodes a log with the ABI of its event and passes it to the event handler.
+func (listener *ContractEventListener) parseAndProcessLog(ctx context.Context, vLog types.Log) (Event, error) {
+	if len(vLog.Topics) == 0 {
+		return nil, fmt.Errorf("%w: anonymous log in TxHash %s", ErrUnhandledEvent, vLog.TxHash.Hex())
+	}
+
+	handler, ok := listener.handlers[vLog.Topics[0]]
+	if !ok {
+		return nil, fmt.Errorf("%w: no handler for event %s in TxHash %s", ErrUnhandledEvent, vLog.Topics[0].Hex(), vLog.TxHash.Hex())
+	}
+
+	event, err := decodeEvent(handler.event, vLog, listener.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode %s for TxHash %s: %v", ErrUnprocessableLog, handler.event.Name, vLog.TxHash.Hex(), err)
+	}
+
+	return handler.handle(ctx, event)
+}
+
+// RunListener indexes the events of the contract until the context is cancelled.
+func (listener *ContractEventListener) RunListener(ctx context.Context) error {
+	for {
+		err := listener.initCursor(ctx)
+		if err == nil {
+			break
+		}
+		log.LG.Errorf("Failed to initialize the block cursor of listener %s: %v", listener.Key, err)
+		listener.recordError(err)
+		if !sleepContext(ctx, RetryDelay) {
+			return nil
+		}
+	}
+	return listener.BaseEventListener.RunListener(ctx, listener.parseAndProcessLog, listener.rollback)
+}
+
+// initCursor takes over the legacy block cursor if the listener inherits it, and sets the next block to process
+// from the cursor and the start block. This is left to the live listener, as it writes the block state and may
+// query the chain at length, which the other users of the listener must not do.
+func (listener *ContractEventListener) initCursor(ctx context.Context) error {
+	if listener.legacyCursor {
+		// Claimed before the cursor is loaded, so that the listener resumes where the single listener stopped
+		if err := listener.LastBlockRepo.ClaimLegacyBlockState(ctx, listener.Key); err != nil {
+			return fmt.Errorf("failed to claim legacy block cursor: %w", err)
+		}
+	}
+
+	lastBlock, err := listener.LastBlockRepo.GetLastProcessedBlock(ctx, listener.Key)
+	if err != nil {
+		return fmt.Errorf("failed to get last processed block: %w", err)
+	}
+
+	startBlock := listener.startBlock
+	if listener.resolveStartBlock != nil && lastBlock == 0 {
+		// The own start block of a listener only applies until it has a cursor
+		if startBlock, err = listener.configuredStartBlock(ctx); err != nil {
+			return err
+		}
+		log.LG.Infof("Listener %s starts at block %d", listener.Key, *startBlock)
+	}
+
+	currentBlock := lastBlock + 1
+	if startBlock != nil && *startBlock > lastBlock {
+		currentBlock = *startBlock
+	}
+	listener.CurrentBlock = currentBlock
+	listener.setCurrentBlock(currentBlock)
+	return nil
+}
+
+// Reset makes the listener start over from its start block, forgetting its cursor, block hashes and published
+// events, and returns the start block. The reset is applied by the listener before its next block chunk, even while paused.
+func (listener *ContractEventListener) Reset(ctx context.Context) (uint64, error) {
+	startBlock, err := listener.configuredStartBlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	block := uint64(1) // Where a listener without cursor nor start block starts
+	if startBlock != nil {
+		block = *startBlock
+	}
+	listener.reset(block)
+	return block, nil
+}
+
+// configuredStartBlock returns the own start block of the listener, or else the start block of the registry,
+// which may be nil.
+func (listener *ContractEventListener) configuredStartBlock(ctx context.Context) (*uint64, error) {
+	if listener.resolveStartBlock == nil {
+		return listener.startBlock, nil
+	}
+
+	startBlock, err := listener.resolveStartBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve start block: %w", err)
+	}
+	return &startBlock, nil
+}
+
+// ListenerRegistry creates the contract event listeners from their definitions and runs them.
+// The listeners share the client, the block cursors repository and the event sinks.
+type ListenerRegistry struct {
+	ETHClient          EthClient
+	ChainID            uint64
+	LastBlockRepo      interfaces.BlockStateRepository
+	UnitOfWork         interfaces.UnitOfWork
+	StartBlockListener *uint64
+	ConfirmationDepth  uint64
+	Sinks              []EventSink
+	SubscribeLogs      bool   // Whether the listeners subscribe to their logs, which requires a WebSocket client
+	MinBlockRange      uint64 // Smallest number of blocks the listeners query logs for at once
+	MaxBlockRange      uint64 // Largest number of blocks the listeners query logs for at once
+	Listeners          []*ContractEventListener
+}
+
+// NewListenerRegistry initializes an empty listener registry.
+func NewListenerRegistry(
+	client EthClient,
+	chainID uint64,
+	lastBlockRepo interfaces.BlockStateRepository,
+	unitOfWork interfaces.UnitOfWork,
+	startBlockListener *uint64,
+	confirmationDepth uint64,
+	sinks []EventSink,
+) *ListenerRegistry {
+	return &ListenerRegistry{
+		ETHClient:          client,
+		ChainID:            chainID,
+		LastBlockRepo:      lastBlockRepo,
+		UnitOfWork:         unitOfWork,
+		StartBlockListener: startBlockListener,
+		ConfirmationDepth:  confirmationDepth,
+		Sinks:              sinks,
+	}
+}
+
+// Register creates the listener of a definition. It fails when the ABI cannot be loaded or does not declare
+// one of the handled events.
+func (registry *ListenerRegistry) Register(definition ListenerDefinition) (*ContractEventListener, error) {
+	if definition.Name != "" && registry.Listener(definition.Name) != nil {
+		return nil, fmt.Errorf("listener %s is already registered", definition.Name)
+	}
+	if len(definition.Handlers) == 0 {
+		return nil, fmt.Errorf("listener of %s has no event handler", definition.ContractAddress)
+	}
+
+	abiFilePath, err := filepath.Abs(definition.ABIFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ABI file path: %w", err)
+	}
+
+	parsedABI, err := loadABI(abiFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ABI: %w", err)
+	}
+
+	handlers := make(map[common.Hash]registeredHandler, len(definition.Handlers))
+	eventNames := make([]string, 0, len(definition.Handlers))
+	for eventName, handle := range definition.Handlers {
+		abiEvent, ok := parsedABI.Events[eventName]
+		if !ok {
+			return nil, fmt.Errorf("event %s is not declared in %s", eventName, definition.ABIFile)
+		}
+		handlers[abiEvent.ID] = registeredHandler{event: abiEvent, handle: handle}
+		eventNames = append(eventNames, eventName)
+	}
+
+	// The own start block of a listener replaces the one of the registry
+	startBlock := registry.StartBlockListener
+	if definition.StartBlock != nil {
+		startBlock = nil
+	}
+
+	baseListener := NewBaseEventListener(
+		registry.ETHClient,
+		registry.ChainID,
+		definition.ContractAddress,
+		parsedABI,
+		eventNames,
+		registry.LastBlockRepo,
+		registry.UnitOfWork,
+		startBlock,
+		registry.ConfirmationDepth,
+	)
+	baseListener.Sinks = registry.Sinks
+	baseListener.SubscribeLogs = registry.SubscribeLogs
+	baseListener.MinBlockRange = registry.MinBlockRange
+	baseListener.MaxBlockRange = registry.MaxBlockRange
+
+	listener := &ContractEventListener{
+		BaseEventListener: baseListener,
+		Name:              definition.Name,
+		handlers:          handlers,
+		rollback:          definition.Rollback,
+		legacyCursor:      definition.LegacyCursor,
+		startBlock:        startBlock,
+		resolveStartBlock: definition.StartBlock,
+	}
+	registry.Listeners = append(registry.Listeners, listener)
+	return listener, nil
+}
+
+// Listener returns the registered listener with the given name, or nil if there is none.
+func (registry *ListenerRegistry) Listener(name string) *ContractEventListener {
+	for _, listener := range registry.Listeners {
+		if listener.Name == name {
+			return listener
+		}
+	}
+	return nil
+}
+
+// Run runs every registered listener until the context is cancelled.
+func (registry *ListenerRegistry) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, listener := range registry.Listeners {
+		wg.Add(1)
+		go func(listener *ContractEventListener) {
+			defer wg.Done()
+			if err := listener.RunListener(ctx); err != nil {
+				log.LG.Errorf("Error running event listener %s: %v", listener.Key, err)
+			}
+		}(listener)
+	}
+	wg.Wait()
+}
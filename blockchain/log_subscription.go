@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// SubscriptionIdleTimeout is the longest a subscribed listener waits for a log before checking the chain
+// anyway, so that its cursor keeps moving over the blocks without events.
+const SubscriptionIdleTimeout = time.Minute
+
+// errSubscriptionClosed is returned when the node closes a subscription without reporting an error.
+var errSubscriptionClosed = errors.New("subscription closed")
+
+// IsWebSocketURL reports whether the RPC endpoint is a WebSocket endpoint, which supports subscriptions.
+func IsWebSocketURL(rpcURL string) bool {
+	rpcURL = strings.ToLower(rpcURL)
+	return strings.HasPrefix(rpcURL, "ws://") || strings.HasPrefix(rpcURL, "wss://")
+}
+
+// logSubscription wakes a listener up as soon as the node pushes a log of the indexed events. The logs
+// themselves are still read with FilterLogs from the stored cursor, so that the logs pushed while the
+// subscription was down are caught up on and reorgs are detected as usual.
+type logSubscription struct {
+	client            EthClient
+	query             ethereum.FilterQuery
+	confirmationDepth uint64
+	wakeChan          chan struct{}
+	connected         atomic.Bool
+}
+
+func newLogSubscription(client EthClient, contractAddr common.Address, eventIDs []common.Hash, confirmationDepth uint64) *logSubscription {
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{contractAddr},
+	}
+	if len(eventIDs) > 0 {
+		query.Topics = [][]common.Hash{eventIDs}
+	}
+
+	return &logSubscription{
+		client:            client,
+		query:             query,
+		confirmationDepth: confirmationDepth,
+		wakeChan:          make(chan struct{}, 1),
+	}
+}
+
+// run keeps the subscription up until the context is cancelled, resubscribing after a disconnect.
+// The listener falls back to polling while the subscription is down.
+func (subscription *logSubscription) run(ctx context.Context) {
+	for {
+		err := subscription.subscribe(ctx)
+		subscription.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+		log.LG.Warnf("Log subscription of %s lost, falling back to polling: %v", subscription.query.Addresses[0].Hex(), err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(RetryDelay):
+		}
+	}
+}
+
+// subscribe subscribes to the logs and wakes the listener up on each of them, until the subscription fails.
+// When blocks need confirmations, the listener is only woken up once the block of a pushed log is confirmed,
+// which requires following the new heads as well.
+func (subscription *logSubscription) subscribe(ctx context.Context) error {
+	logs := make(chan types.Log, DefaultEventChannelBufferSize)
+	logSub, err := subscription.client.SubscribeFilterLogs(ctx, subscription.query, logs)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to logs: %w", err)
+	}
+	defer logSub.Unsubscribe()
+
+	var heads chan *types.Header
+	var headErrs <-chan error
+	if subscription.confirmationDepth > 0 {
+		heads = make(chan *types.Header, DefaultEventChannelBufferSize)
+		headSub, err := subscription.client.SubscribeNewHead(ctx, heads)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to new heads: %w", err)
+		}
+		defer headSub.Unsubscribe()
+		headErrs = headSub.Err()
+	}
+
+	subscription.connected.Store(true)
+	log.LG.Infof("Subscribed to the logs of %s", subscription.query.Addresses[0].Hex())
+
+	// Catch up on the logs emitted while the subscription was down
+	subscription.wake()
+
+	pendingBlock := uint64(0) // Highest block with a pushed log that is not confirmed yet
+	for {
+		select {
+		case vLog := <-logs:
+			if subscription.confirmationDepth == 0 || vLog.Removed {
+				// Removed logs are woken up on straight away, for the listener to detect the reorg
+				subscription.wake()
+			} else if vLog.BlockNumber > pendingBlock {
+				pendingBlock = vLog.BlockNumber
+			}
+
+		case head := <-heads:
+			if pendingBlock > 0 && head.Number.Uint64() >= pendingBlock+subscription.confirmationDepth {
+				pendingBlock = 0
+				subscription.wake()
+			}
+
+		case err := <-logSub.Err():
+			if err == nil {
+				err = errSubscriptionClosed
+			}
+			return err
+
+		case err := <-headErrs:
+			if err == nil {
+				err = errSubscriptionClosed
+			}
+			return err
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// wake wakes the listener up, unless a wake-up is already pending.
+func (subscription *logSubscription) wake() {
+	select {
+	case subscription.wakeChan <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,75 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OnChainMembership is the membership state of a user as stored by the membership contract.
+type OnChainMembership struct {
+	Active     bool      // Result of isMembershipActive
+	IsMember   bool      // isMember field of members
+	Expiration time.Time // expiration field of members
+}
+
+// MembershipContract reads the view functions of the membership contract.
+type MembershipContract struct {
+	contract *bind.BoundContract
+}
+
+// NewMembershipContract binds the membership contract at the given address.
+func NewMembershipContract(client EthClient, contractAddr string) (*MembershipContract, error) {
+	abiFilePath, err := filepath.Abs("./contracts/abis/MembershipPurchase.abi.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ABI file path: %w", err)
+	}
+
+	parsedABI, err := loadABI(abiFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ABI: %w", err)
+	}
+
+	return &MembershipContract{
+		contract: bind.NewBoundContract(common.HexToAddress(contractAddr), parsedABI, client, client, client),
+	}, nil
+}
+
+// GetMembership returns the membership state of the user according to the contract.
+func (membership *MembershipContract) GetMembership(ctx context.Context, user common.Address) (*OnChainMembership, error) {
+	opts := &bind.CallOpts{Context: ctx}
+
+	var activeResult []interface{}
+	if err := membership.contract.Call(opts, &activeResult, "isMembershipActive", user); err != nil {
+		return nil, fmt.Errorf("failed to call isMembershipActive: %w", err)
+	}
+
+	var memberResult []interface{}
+	if err := membership.contract.Call(opts, &memberResult, "members", user); err != nil {
+		return nil, fmt.Errorf("failed to call members: %w", err)
+	}
+
+	active, ok := activeResult[0].(bool)
+	if !ok {
+		return nil, fmt.Errorf("unexpected isMembershipActive result: %v", activeResult)
+	}
+	isMember, ok := memberResult[0].(bool)
+	if !ok {
+		return nil, fmt.Errorf("unexpected members result: %v", memberResult)
+	}
+	expiration, ok := memberResult[1].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected members result: %v", memberResult)
+	}
+
+	return &OnChainMembership{
+		Active:     active,
+		IsMember:   isMember,
+		Expiration: time.Unix(expiration.Int64(), 0).UTC(),
+	}, nil
+}
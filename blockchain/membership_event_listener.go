@@ -2,122 +2,140 @@ package blockchain
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
-	"path/filepath"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"github.com/genefriendway/onchain-handler/internal/constants"
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
 	"github.com/genefriendway/onchain-handler/internal/model"
 	"github.com/genefriendway/onchain-handler/internal/utils/log"
 )
 
+const (
+	MembershipPurchasedEvent = "MembershipPurchased"                          // Name of the event indexed by the MembershipEventListener
+	MembershipABIFile        = "./contracts/abis/MembershipPurchase.abi.json" // ABI of the membership contract
+	MembershipListenerName   = "membership"                                   // Name of the MembershipEventListener
+)
+
 // MembershipEventData represents the event data for a MembershipPurchased event.
 type MembershipEventData struct {
-	User     common.Address
-	Amount   *big.Int
-	OrderID  uint64
-	TxHash   string
-	Duration uint8 // Duration as an integer representing the type (0 for 1 year, 1 for 3 years)
+	User     common.Address `json:"user"`
+	Amount   *big.Int       `json:"-"` // Encoded as a decimal string by MarshalJSON, as it exceeds the precision of JSON numbers
+	OrderID  uint64         `json:"order_id"`
+	TxHash   string         `json:"transaction_hash"`
+	Duration uint8          `json:"duration"` // Duration as an integer representing the type (0 for 1 year, 1 for 3 years)
+}
+
+func (e *MembershipEventData) MarshalJSON() ([]byte, error) {
+	type eventData MembershipEventData
+	return json.Marshal(struct {
+		*eventData
+		Amount string `json:"amount"`
+	}{
+		eventData: (*eventData)(e),
+		Amount:    e.Amount.String(),
+	})
+}
+
+func (e *MembershipEventData) EventName() string {
+	return MembershipPurchasedEvent
+}
+
+// EventID identifies the purchase by its transaction, which the membership contract emits a single event for.
+func (e *MembershipEventData) EventID() string {
+	return e.TxHash
 }
 
-// MembershipEventListener listens for MembershipPurchased events.
+// MembershipEventListener indexes MembershipPurchased events.
 type MembershipEventListener struct {
-	*BaseEventListener
 	Repo interfaces.MembershipRepository
 }
 
 // NewMembershipEventListener initializes the membership event listener.
-func NewMembershipEventListener(
-	client *ethclient.Client,
-	contractAddr string,
-	repo interfaces.MembershipRepository,
-	lastBlockRepo interfaces.BlockStateRepository,
-	startBlockListener *uint64,
-) (*MembershipEventListener, error) {
-	abiFilePath, err := filepath.Abs("./contracts/abis/MembershipPurchase.abi.json")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get ABI file path: %w", err)
+func NewMembershipEventListener(repo interfaces.MembershipRepository) *MembershipEventListener {
+	return &MembershipEventListener{
+		Repo: repo,
 	}
+}
 
-	parsedABI, err := loadABI(abiFilePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load ABI: %w", err)
+// Definition declares the listener of the membership contract at the given address.
+func (listener *MembershipEventListener) Definition(contractAddr string) ListenerDefinition {
+	return ListenerDefinition{
+		Name:            MembershipListenerName,
+		ABIFile:         MembershipABIFile,
+		ContractAddress: contractAddr,
+		Handlers: map[string]EventHandler{
+			MembershipPurchasedEvent: listener.handleMembershipPurchased,
+		},
+		Rollback:     listener.rollbackMembershipEvents,
+		LegacyCursor: true, // The membership listener was the only listener before per-listener cursors
 	}
-
-	baseListener := NewBaseEventListener(client, contractAddr, parsedABI, lastBlockRepo, startBlockListener)
-	return &MembershipEventListener{
-		BaseEventListener: baseListener,
-		Repo:              repo,
-	}, nil
 }
 
-// parseAndProcessMembershipEvent handles MembershipPurchased event-specific logic.
-func (listener *MembershipEventListener) parseAndProcessMembershipEvent(vLog types.Log) (interface{}, error) {
+// handleMembershipPurchased handles MembershipPurchased event-specific logic.
+func (listener *MembershipEventListener) handleMembershipPurchased(ctx context.Context, decoded *DecodedEvent) (Event, error) {
 	event := struct {
 		User     common.Address
 		Amount   *big.Int
-		OrderID  uint64
+		OrderId  uint64
 		Duration uint8
 	}{}
-
-	// Unpack the log data into the event structure.
-	err := listener.ParsedABI.UnpackIntoInterface(&event, "MembershipPurchased", vLog.Data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unpack log for TxHash %s: %w", vLog.TxHash.Hex(), err)
+	if err := decoded.Decode(&event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnprocessableLog, err)
 	}
+	vLog := decoded.Log
 
-	// Extract indexed fields (user address and order ID).
-	event.User = common.HexToAddress(vLog.Topics[1].Hex())
-
-	var endDuration time.Time
-	switch event.Duration {
-	case 0:
-		endDuration = time.Now().AddDate(0, 0, 365) // Add 365 days
-	case 1:
-		endDuration = time.Now().AddDate(0, 0, 1095) // Add 1095 days (3 years)
-	default:
-		log.LG.Errorf("Invalid duration value: %d for OrderID %d", event.Duration, event.OrderID)
-		return nil, fmt.Errorf("invalid duration value: %d", event.Duration)
+	durationDays, ok := constants.MembershipDurationDays[event.Duration]
+	if !ok {
+		log.LG.Errorf("Invalid duration value: %d for OrderID %d", event.Duration, event.OrderId)
+		return nil, fmt.Errorf("%w: invalid duration value: %d", ErrUnprocessableLog, event.Duration)
 	}
 
-	orderID, err := parseHexToUint64(vLog.Topics[2].Hex())
+	// Memberships start when the purchase was mined, whenever the event gets indexed
+	blockTime, err := decoded.BlockTime(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse order ID: %w", err)
+		return nil, err
 	}
+	endDuration := blockTime.AddDate(0, 0, durationDays)
 
 	eventModel := model.MembershipEvent{
 		UserAddress:     event.User.Hex(),
-		OrderID:         orderID,
+		OrderID:         event.OrderId,
 		TransactionHash: vLog.TxHash.Hex(),
 		Amount:          event.Amount.String(),
-		Status:          1,
+		Status:          constants.MembershipEventSuccess,
 		EndDuration:     endDuration,
+		Duration:        event.Duration,
+		BlockNumber:     vLog.BlockNumber,
+		BlockHash:       vLog.BlockHash.Hex(),
+		BlockTimestamp:  &blockTime,
+		LogIndex:        vLog.Index,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 
 	// Store event in the repository
 	// Handle duplicate transaction errors gracefully
-	err = listener.Repo.CreateMembershipEventHistory(context.Background(), eventModel)
+	err = listener.Repo.CreateMembershipEventHistory(ctx, eventModel)
 	if err != nil {
 		if isDuplicateTransactionError(err) {
+			// The purchase was already indexed and published, e.g. by a backfill over indexed blocks
 			log.LG.Warnf("Duplicate transaction detected for TxHash %s: %v", vLog.TxHash.Hex(), err)
-		} else {
-			log.LG.Errorf("Failed to create membership event history for OrderID %d: %v", event.OrderID, err)
-			return nil, err
+			return nil, nil
 		}
+		log.LG.Errorf("Failed to create membership event history for OrderID %d: %v", event.OrderId, err)
+		return nil, err
 	}
 
 	// Create event data.
 	eventData := &MembershipEventData{
 		User:     event.User,
 		Amount:   event.Amount,
-		OrderID:  orderID,
+		OrderID:  event.OrderId,
 		Duration: event.Duration,
 		TxHash:   vLog.TxHash.Hex(),
 	}
@@ -125,8 +143,13 @@ func (listener *MembershipEventListener) parseAndProcessMembershipEvent(vLog typ
 	return eventData, nil
 }
 
-// RunListener starts the listener with specific event processing logic.
-func (listener *MembershipEventListener) RunListener(ctx context.Context) error {
-	// Pass the specific event parsing function.
-	return listener.BaseEventListener.RunListener(ctx, listener.parseAndProcessMembershipEvent)
+// rollbackMembershipEvents marks membership events from blocks replaced by a chain reorg as orphaned.
+func (listener *MembershipEventListener) rollbackMembershipEvents(ctx context.Context, fromBlock uint64) error {
+	orphaned, err := listener.Repo.MarkMembershipEventsOrphanedFromBlock(ctx, fromBlock)
+	if err != nil {
+		return fmt.Errorf("failed to mark membership events from block %d as orphaned: %w", fromBlock, err)
+	}
+
+	log.LG.Warnf("Marked %d membership events from block %d onwards as orphaned", orphaned, fromBlock)
+	return nil
 }
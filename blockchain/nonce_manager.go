@@ -0,0 +1,124 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// NonceGapTimeout is how long the chain's pending nonce may stay below the managed nonce
+// before the missing transactions are considered dropped and the nonce is resynced.
+// The nonce is not resynced while recorded transactions still wait on the missing nonces.
+const NonceGapTimeout = time.Minute
+
+// NonceManager hands out sequential nonces for a signing account. The next nonce is persisted
+// so that it survives restarts, and resynced with the chain when they drift apart.
+type NonceManager struct {
+	ETHClient EthClient
+	Repo      interfaces.NonceRepository
+	Transfers interfaces.TransferRepository // Recorded transactions holding the nonces handed out
+	ChainID   uint64
+	Address   common.Address
+
+	mu         sync.Mutex
+	nextNonce  uint64
+	loaded     bool
+	lastIssued time.Time
+}
+
+// NewNonceManager initializes the nonce manager of the given account.
+func NewNonceManager(
+	client EthClient,
+	repo interfaces.NonceRepository,
+	transfers interfaces.TransferRepository,
+	chainID uint64,
+	address string,
+) *NonceManager {
+	return &NonceManager{
+		ETHClient: client,
+		Repo:      repo,
+		Transfers: transfers,
+		ChainID:   chainID,
+		Address:   common.HexToAddress(address),
+	}
+}
+
+// WithNonce reserves the next nonce of the account and passes it to send, which must sign and broadcast
+// one transaction with it. Calls are serialized, so concurrent callers never race for the same nonce.
+// send reports whether the nonce is consumed: it is once the transaction was broadcast, and also once
+// the transaction was recorded, even if its broadcast then failed, since it may still have reached the
+// node and must not be replaced by another transaction with the same nonce.
+func (manager *NonceManager) WithNonce(ctx context.Context, send func(nonce uint64) (bool, error)) error {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	nonce, err := manager.sync(ctx)
+	if err != nil {
+		return err
+	}
+
+	consumed, err := send(nonce)
+	if err != nil && isNonceError(err) {
+		// Reload from the database and the chain on the next call
+		manager.loaded = false
+	}
+	if !consumed {
+		return err
+	}
+
+	manager.nextNonce = nonce + 1
+	manager.lastIssued = time.Now()
+	if saveErr := manager.Repo.SaveNextNonce(ctx, manager.ChainID, manager.Address.Hex(), manager.nextNonce); saveErr != nil {
+		// The transaction is already recorded, and the chain resync recovers from the lost write.
+		log.LG.Errorf("Failed to persist next nonce %d of %s: %v", manager.nextNonce, manager.Address.Hex(), saveErr)
+	}
+	return err
+}
+
+// sync returns the next nonce to use, reconciling the managed nonce with the chain's pending nonce.
+func (manager *NonceManager) sync(ctx context.Context) (uint64, error) {
+	if !manager.loaded {
+		storedNonce, err := manager.Repo.GetNextNonce(ctx, manager.ChainID, manager.Address.Hex())
+		if err != nil {
+			return 0, err
+		}
+		manager.nextNonce = storedNonce
+		manager.loaded = true
+		// The transactions sent before a restart get the same grace period as fresh ones
+		manager.lastIssued = time.Now()
+	}
+
+	pendingNonce, err := manager.ETHClient.PendingNonceAt(ctx, manager.Address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	switch {
+	case pendingNonce > manager.nextNonce:
+		// Transactions were sent from the account outside of this manager
+		log.LG.Warnf("Nonce of %s is behind the chain (%d < %d). Resyncing", manager.Address.Hex(), manager.nextNonce, pendingNonce)
+		manager.nextNonce = pendingNonce
+	case pendingNonce < manager.nextNonce && time.Since(manager.lastIssued) > NonceGapTimeout:
+		// Transactions handed out earlier never reached the mempool, leaving a gap that blocks later ones.
+		// Recorded transactions are rebroadcast by the transaction tracker, or marked as dropped once the
+		// node is known not to have them, so their nonces are only handed out again after that.
+		waiting, err := manager.Transfers.HasPendingTransfersInNonceRange(ctx, manager.Address.Hex(), pendingNonce, manager.nextNonce)
+		if err != nil {
+			return 0, err
+		}
+		if waiting {
+			log.LG.Warnf("Nonce gap detected for %s (%d > %d), waiting for its pending transactions", manager.Address.Hex(), manager.nextNonce, pendingNonce)
+			break
+		}
+		log.LG.Warnf("Nonce gap detected for %s (%d > %d). Resyncing", manager.Address.Hex(), manager.nextNonce, pendingNonce)
+		manager.nextNonce = pendingNonce
+	}
+
+	return manager.nextNonce, nil
+}
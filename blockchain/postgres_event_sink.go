@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+)
+
+// PostgresEventSink stores the events in the contract_event table, which downstream services can read
+// or LISTEN to on the contract_event channel. Events removed from the chain by a reorg are stored again
+// with removed set.
+type PostgresEventSink struct {
+	Repo interfaces.ContractEventRepository
+}
+
+func NewPostgresEventSink(repo interfaces.ContractEventRepository) *PostgresEventSink {
+	return &PostgresEventSink{
+		Repo: repo,
+	}
+}
+
+func (sink *PostgresEventSink) Name() string {
+	return "postgres"
+}
+
+func (sink *PostgresEventSink) Transactional() bool {
+	return true
+}
+
+func (sink *PostgresEventSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.EventName(), err)
+	}
+
+	return sink.Repo.CreateContractEvent(ctx, model.ContractEvent{
+		EventName: event.EventName(),
+		EventID:   event.EventID(),
+		Payload:   string(payload),
+	})
+}
+
+func (sink *PostgresEventSink) Retract(ctx context.Context, event *RetractedEvent) error {
+	return sink.Repo.RemoveContractEvent(ctx, model.ContractEvent{
+		EventName: event.EventName(),
+		EventID:   event.EventID(),
+		Payload:   string(event.Payload),
+	})
+}
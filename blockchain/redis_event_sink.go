@@ -0,0 +1,76 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultRedisStreamPrefix = "onchain-handler:events" // Prefix of the streams, followed by ":<event name>"
+	DefaultRedisStreamMaxLen = 10000                    // Approximate number of entries kept per stream
+)
+
+// RedisStreamEventSink appends the events to a Redis stream per event name, e.g. "onchain-handler:events:MembershipPurchased".
+// Each entry has the fields "id", "type" and "payload", the JSON encoded event. Events removed from the chain by a
+// reorg are appended again with the field "removed" set to "true".
+type RedisStreamEventSink struct {
+	Client *redis.Client
+	Prefix string
+	MaxLen int64
+}
+
+func NewRedisStreamEventSink(client *redis.Client, prefix string, maxLen int64) *RedisStreamEventSink {
+	if prefix == "" {
+		prefix = DefaultRedisStreamPrefix
+	}
+	if maxLen <= 0 {
+		maxLen = DefaultRedisStreamMaxLen
+	}
+	return &RedisStreamEventSink{
+		Client: client,
+		Prefix: prefix,
+		MaxLen: maxLen,
+	}
+}
+
+func (sink *RedisStreamEventSink) Name() string {
+	return "redis"
+}
+
+func (sink *RedisStreamEventSink) Transactional() bool {
+	return false
+}
+
+func (sink *RedisStreamEventSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.EventName(), err)
+	}
+
+	return sink.add(ctx, event, map[string]interface{}{
+		"id":      event.EventID(),
+		"type":    event.EventName(),
+		"payload": string(payload),
+	})
+}
+
+func (sink *RedisStreamEventSink) Retract(ctx context.Context, event *RetractedEvent) error {
+	return sink.add(ctx, event, map[string]interface{}{
+		"id":      event.EventID(),
+		"type":    event.EventName(),
+		"payload": string(event.Payload),
+		"removed": "true",
+	})
+}
+
+func (sink *RedisStreamEventSink) add(ctx context.Context, event Event, values map[string]interface{}) error {
+	return sink.Client.XAdd(ctx, &redis.XAddArgs{
+		Stream: fmt.Sprintf("%s:%s", sink.Prefix, event.EventName()),
+		MaxLen: sink.MaxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
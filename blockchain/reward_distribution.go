@@ -1,11 +1,15 @@
 package blockchain
 
 import (
+	"context"
 	"fmt"
 	"math/big"
+	"sort"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/genefriendway/onchain-handler/conf"
 	"github.com/genefriendway/onchain-handler/contracts/abigen/lifepointtoken"
@@ -13,8 +17,39 @@ import (
 	"github.com/genefriendway/onchain-handler/internal/utils/log"
 )
 
-// DistributeReward distributes reward tokens from the reward address to user wallets using bulk transfer
-func DistributeReward(client *ethclient.Client, config *conf.Configuration, recipients map[string]*big.Int) (*string, error) {
+const (
+	DefaultMaxGasPerTx           = 5000000 // Gas budget of a single bulk transfer transaction
+	DefaultGasLimitBufferPercent = 20      // Percentage added on top of the gas estimate of a bulk transfer
+)
+
+// RewardBatch is a group of recipients paid by a single bulk transfer transaction.
+type RewardBatch struct {
+	Index       int
+	Recipients  []common.Address
+	Amounts     []*big.Int
+	GasLimit    uint64
+	Transaction *types.Transaction // Nil when the batch could not be sent
+	Err         error              // Reason the batch could not be sent
+}
+
+// BeforeSendFunc is called with a signed batch transaction right before it is broadcast,
+// so that the caller can record it. The transaction is not broadcast when it returns an error.
+type BeforeSendFunc func(ctx context.Context, batch *RewardBatch) error
+
+// DistributeReward distributes reward tokens from the reward address to user wallets using bulk transfers.
+// The recipients are split into batches whose estimated gas fits under the per-transaction gas budget and
+// the block gas limit, and each batch is sent in its own transaction. A batch that fails does not prevent
+// the others from being sent, so the outcome of each batch is reported on the returned batches.
+// A batch whose transaction was signed but could not be broadcast carries both its transaction and its error.
+func DistributeReward(
+	ctx context.Context,
+	client EthClient,
+	config *conf.Configuration,
+	nonceManager *NonceManager,
+	feeStrategy FeeStrategy,
+	recipients map[string]*big.Int,
+	beforeSend BeforeSendFunc,
+) ([]RewardBatch, error) {
 	// Load Blockchain configuration
 	chainID := config.Blockchain.ChainID
 	privateKey := config.Blockchain.PrivateKeyReward
@@ -26,38 +61,174 @@ func DistributeReward(client *ethclient.Client, config *conf.Configuration, reci
 		return nil, fmt.Errorf("failed to get private key: %w", err)
 	}
 
-	auth, err := util.GetAuth(client, privateKeyECDSA, new(big.Int).SetUint64(uint64(chainID)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get auth: %w", err)
-	}
-
 	// Set up the reward token contract instance
 	LPToken, err := lifepointtoken.NewLifepointtoken(common.HexToAddress(tokenAddress), client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to instantiate ERC20 contract: %w", err)
 	}
 
-	// Prepare recipient addresses and values for bulk transfer
-	var recipientAddresses []common.Address
-	var tokenAmounts []*big.Int
-	for recipientAddress, amount := range recipients {
-		recipientAddresses = append(recipientAddresses, common.HexToAddress(recipientAddress))
-		tokenAmounts = append(tokenAmounts, amount)
+	// Prepare recipient addresses and values for bulk transfer, in a stable order
+	recipientAddresses := make([]string, 0, len(recipients))
+	for recipientAddress := range recipients {
+		recipientAddresses = append(recipientAddresses, recipientAddress)
 	}
+	sort.Strings(recipientAddresses)
 
-	// Call the bulkTransfer function in the Solidity contract
-	tx, err := LPToken.BulkTransfer(auth, recipientAddresses, tokenAmounts)
+	batch := RewardBatch{}
+	for _, recipientAddress := range recipientAddresses {
+		batch.Recipients = append(batch.Recipients, common.HexToAddress(recipientAddress))
+		batch.Amounts = append(batch.Amounts, recipients[recipientAddress])
+	}
+
+	planner, err := newBatchPlanner(ctx, client, config)
 	if err != nil {
-		log.LG.Errorf("Failed to execute bulk transfer: %v", err)
 		return nil, err
 	}
+	batches := planner.split(ctx, batch)
+
+	for index := range batches {
+		batches[index].Index = index
+		if batches[index].Err != nil {
+			log.LG.Errorf("Skipping bulk transfer batch %d: %v", index, batches[index].Err)
+			continue
+		}
+
+		// Call the bulkTransfer function in the Solidity contract, signed with the next nonce of the reward account
+		err = nonceManager.WithNonce(ctx, func(nonce uint64) (bool, error) {
+			auth, err := util.GetAuth(privateKeyECDSA, new(big.Int).SetUint64(uint64(chainID)), nonce)
+			if err != nil {
+				return false, fmt.Errorf("failed to get auth: %w", err)
+			}
+			auth.Context = ctx
+			auth.GasLimit = batches[index].GasLimit
+			auth.NoSend = true // Broadcast once the caller has recorded the transaction
+
+			fees, err := feeStrategy.SuggestFees(ctx)
+			if err != nil {
+				return false, fmt.Errorf("failed to get fees: %w", err)
+			}
+			fees.Apply(auth)
+
+			tx, err := LPToken.BulkTransfer(auth, batches[index].Recipients, batches[index].Amounts)
+			if err != nil {
+				return false, err
+			}
+			batches[index].Transaction = tx
+
+			if beforeSend != nil {
+				if err := beforeSend(ctx, &batches[index]); err != nil {
+					return false, fmt.Errorf("failed to record transaction: %w", err)
+				}
+			}
+			err = client.SendTransaction(ctx, tx)
+			// A recorded transaction consumes its nonce even when the broadcast fails: it stays pending
+			// until the transaction tracker settles it.
+			return beforeSend != nil || err == nil, err
+		})
+		if err != nil {
+			log.LG.Errorf("Failed to execute bulk transfer batch %d: %v", index, err)
+			batches[index].Err = err
+			continue
+		}
+
+		// Log the transaction hash for tracking
+		tx := batches[index].Transaction
+		log.LG.Infof("Bulk transfer batch %d/%d executed. Tx hash: %s, nonce: %d, recipients: %d, gas limit: %d",
+			index+1, len(batches), tx.Hash().Hex(), tx.Nonce(), len(batches[index].Recipients), tx.Gas())
+	}
+
+	return batches, nil
+}
+
+// batchPlanner splits bulk transfers into batches that fit under the gas budget of a transaction.
+type batchPlanner struct {
+	client        EthClient
+	from          common.Address
+	tokenAddress  common.Address
+	tokenABI      *abi.ABI
+	gasBudget     uint64
+	bufferPercent uint64
+}
+
+// newBatchPlanner initializes the planner with the configured gas budget, lowered to the gas limit
+// of the latest block if needed.
+func newBatchPlanner(ctx context.Context, client EthClient, config *conf.Configuration) (*batchPlanner, error) {
+	tokenABI, err := lifepointtoken.LifepointtokenMetaData.GetAbi()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token ABI: %w", err)
+	}
+
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the latest block header: %w", err)
+	}
+
+	gasBudget := config.Blockchain.MaxGasPerTx
+	if gasBudget == 0 {
+		gasBudget = DefaultMaxGasPerTx
+	}
+	if header.GasLimit < gasBudget {
+		gasBudget = header.GasLimit
+	}
+
+	bufferPercent := config.Blockchain.GasLimitBufferPercent
+	if bufferPercent == 0 {
+		bufferPercent = DefaultGasLimitBufferPercent
+	}
+
+	return &batchPlanner{
+		client:        client,
+		from:          common.HexToAddress(config.Blockchain.RewardAddress),
+		tokenAddress:  common.HexToAddress(config.Blockchain.LifePointAddress),
+		tokenABI:      tokenABI,
+		gasBudget:     gasBudget,
+		bufferPercent: bufferPercent,
+	}, nil
+}
+
+// split estimates the gas of the batch and halves it until every part fits under the gas budget.
+// A batch that cannot be estimated, or a single recipient that does not fit, is returned carrying the error.
+func (planner *batchPlanner) split(ctx context.Context, batch RewardBatch) []RewardBatch {
+	if len(batch.Recipients) == 0 {
+		return nil
+	}
 
-	// Get the transaction hash after a successful transfer
-	txHash := tx.Hash().Hex()
+	gasLimit, err := planner.estimateGasLimit(ctx, batch)
+	switch {
+	case err == nil && gasLimit <= planner.gasBudget:
+		batch.GasLimit = gasLimit
+		return []RewardBatch{batch}
+	case err != nil && (len(batch.Recipients) == 1 || isRevertError(err)):
+		// Splitting does not help when the transfer itself reverts
+		batch.Err = err
+		return []RewardBatch{batch}
+	case err == nil && len(batch.Recipients) == 1:
+		batch.Err = fmt.Errorf("gas limit %d of a single transfer exceeds the gas budget of %d", gasLimit, planner.gasBudget)
+		return []RewardBatch{batch}
+	}
+
+	// Either over the budget, or over the gas cap of the node's estimation
+	middle := len(batch.Recipients) / 2
+	head := planner.split(ctx, RewardBatch{Recipients: batch.Recipients[:middle], Amounts: batch.Amounts[:middle]})
+	tail := planner.split(ctx, RewardBatch{Recipients: batch.Recipients[middle:], Amounts: batch.Amounts[middle:]})
+	return append(head, tail...)
+}
 
-	// Log the transaction hash for tracking
-	log.LG.Infof("Bulk transfer executed. Tx hash: %s\n", txHash)
+// estimateGasLimit estimates the gas used by the bulk transfer of the batch, plus the safety buffer.
+func (planner *batchPlanner) estimateGasLimit(ctx context.Context, batch RewardBatch) (uint64, error) {
+	data, err := planner.tokenABI.Pack("bulkTransfer", batch.Recipients, batch.Amounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack bulk transfer: %w", err)
+	}
+
+	gas, err := planner.client.EstimateGas(ctx, ethereum.CallMsg{
+		From: planner.from,
+		To:   &planner.tokenAddress,
+		Data: data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas of bulk transfer to %d recipients: %w", len(batch.Recipients), err)
+	}
 
-	// Return success with the transaction hash
-	return &txHash, nil
+	return gas + gas*planner.bufferPercent/100, nil
 }
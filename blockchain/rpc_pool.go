@@ -0,0 +1,529 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/genefriendway/onchain-handler/conf"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultRPCHealthCheckInterval = 15 * time.Second // Delay between two health checks of the endpoints
+	DefaultRPCMaxBlockLag         = 10               // Number of blocks an endpoint may lag behind the others and stay healthy
+	RPCRequestTimeout             = 10 * time.Second // Timeout of the health checks and of each endpoint of a quorum read
+	rpcScoreSmoothing             = 0.2              // Weight of the last call in the moving averages of the endpoint scores
+	rpcLimitExceededCode          = -32005           // JSON-RPC error code of rate limited requests
+)
+
+var (
+	// ErrNoRPCEndpoint is returned when no endpoint of the pool is connected.
+	ErrNoRPCEndpoint = errors.New("no RPC endpoint available")
+	// ErrRPCQuorum is returned when too few endpoints answered a quorum read.
+	ErrRPCQuorum = errors.New("RPC quorum not reached")
+)
+
+// rpcEndpoint is an endpoint of the pool along with its health and score.
+type rpcEndpoint struct {
+	url  string // May contain an API key, never logged
+	name string // Scheme and host of the url, for the logs
+
+	mu        sync.Mutex
+	client    *ethclient.Client // Nil until the endpoint could be dialled
+	healthy   bool
+	latency   time.Duration // Moving average of the call latency
+	errorRate float64       // Moving average of the failed calls, between 0 and 1
+}
+
+func (endpoint *rpcEndpoint) getClient() *ethclient.Client {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	return endpoint.client
+}
+
+// record updates the score of the endpoint with the outcome of a call. A failed call marks the endpoint
+// unhealthy until the next successful health check.
+func (endpoint *rpcEndpoint) record(latency time.Duration, err error) {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+
+	failed := 0.0
+	if err != nil {
+		failed = 1
+		endpoint.healthy = false
+	}
+	endpoint.errorRate = endpoint.errorRate*(1-rpcScoreSmoothing) + failed*rpcScoreSmoothing
+	if endpoint.latency == 0 {
+		endpoint.latency = latency
+	} else {
+		endpoint.latency = time.Duration(float64(endpoint.latency)*(1-rpcScoreSmoothing) + float64(latency)*rpcScoreSmoothing)
+	}
+}
+
+// score ranks the endpoints, the lower the better. An endpoint failing every call scores ten times its latency.
+func (endpoint *rpcEndpoint) score() (bool, float64) {
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	return endpoint.healthy, float64(endpoint.latency) * (1 + 9*endpoint.errorRate)
+}
+
+// RPCPool spreads the calls over several RPC endpoints. Calls go to the healthy endpoint with the best
+// latency and error score, and fail over to the next endpoints when it cannot be reached. Reads of the
+// latest block are checked against a quorum of endpoints.
+type RPCPool struct {
+	endpoints           []*rpcEndpoint
+	Quorum              int           // Number of endpoints that must have reached the latest block
+	HealthCheckInterval time.Duration // Delay between two health checks of the endpoints
+	MaxBlockLag         uint64        // Number of blocks an endpoint may lag behind the others and stay healthy
+}
+
+// NewRPCPool dials the configured endpoints. Endpoints that cannot be dialled are dialled again by the
+// health checks. It fails when none of them can be dialled.
+func NewRPCPool(ctx context.Context, config conf.BlockchainConfiguration) (*RPCPool, error) {
+	pool := &RPCPool{
+		Quorum:              config.RpcQuorum,
+		HealthCheckInterval: config.RpcHealthCheckInterval,
+		MaxBlockLag:         config.RpcMaxBlockLag,
+	}
+	if pool.HealthCheckInterval <= 0 {
+		pool.HealthCheckInterval = DefaultRPCHealthCheckInterval
+	}
+	if pool.MaxBlockLag == 0 {
+		pool.MaxBlockLag = DefaultRPCMaxBlockLag
+	}
+
+	connected := 0
+	for _, rawURL := range config.RpcEndpoints() {
+		endpoint := &rpcEndpoint{url: rawURL, name: endpointName(rawURL)}
+		if err := endpoint.dial(ctx); err != nil {
+			log.LG.Warnf("Failed to connect to RPC endpoint %s: %v", endpoint.name, err)
+		} else {
+			connected++
+		}
+		pool.endpoints = append(pool.endpoints, endpoint)
+	}
+	if connected == 0 {
+		return nil, fmt.Errorf("failed to connect to any of the %d RPC endpoints: %w", len(pool.endpoints), ErrNoRPCEndpoint)
+	}
+
+	if pool.Quorum < 1 {
+		pool.Quorum = 1
+	}
+	if pool.Quorum > len(pool.endpoints) {
+		log.LG.Warnf("RPC quorum %d exceeds the %d endpoints, lowering it", pool.Quorum, len(pool.endpoints))
+		pool.Quorum = len(pool.endpoints)
+	}
+	return pool, nil
+}
+
+// endpointName strips the path and query of an endpoint URL, where providers put the API keys.
+func endpointName(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" {
+		return "<invalid url>"
+	}
+	return parsedURL.Scheme + "://" + parsedURL.Host
+}
+
+func (endpoint *rpcEndpoint) dial(ctx context.Context) error {
+	dialCtx, cancel := context.WithTimeout(ctx, RPCRequestTimeout)
+	defer cancel()
+
+	client, err := ethclient.DialContext(dialCtx, endpoint.url)
+	if err != nil {
+		return err
+	}
+
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+	endpoint.client = client
+	endpoint.healthy = true
+	return nil
+}
+
+// Run checks the health of the endpoints until the context is cancelled.
+func (pool *RPCPool) Run(ctx context.Context) {
+	log.LG.Infof("Checking the health of %d RPC endpoints every %s", len(pool.endpoints), pool.HealthCheckInterval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pool.HealthCheckInterval):
+			pool.checkHealth(ctx)
+		}
+	}
+}
+
+// checkHealth asks every endpoint for its latest block. Endpoints are healthy when they answer and do not
+// lag more than MaxBlockLag blocks behind the most advanced one. Endpoints that were never connected are
+// dialled again.
+func (pool *RPCPool) checkHealth(ctx context.Context) {
+	heads := make([]uint64, len(pool.endpoints))
+	errs := make([]error, len(pool.endpoints))
+
+	var wg sync.WaitGroup
+	for index, endpoint := range pool.endpoints {
+		wg.Add(1)
+		go func(index int, endpoint *rpcEndpoint) {
+			defer wg.Done()
+			if endpoint.getClient() == nil {
+				if err := endpoint.dial(ctx); err != nil {
+					errs[index] = err
+					return
+				}
+				log.LG.Infof("Connected to RPC endpoint %s", endpoint.name)
+			}
+
+			checkCtx, cancel := context.WithTimeout(ctx, RPCRequestTimeout)
+			defer cancel()
+			start := time.Now()
+			heads[index], errs[index] = endpoint.getClient().BlockNumber(checkCtx)
+			endpoint.record(time.Since(start), errs[index])
+		}(index, endpoint)
+	}
+	wg.Wait()
+
+	bestHead := uint64(0)
+	for index := range pool.endpoints {
+		if errs[index] == nil && heads[index] > bestHead {
+			bestHead = heads[index]
+		}
+	}
+
+	for index, endpoint := range pool.endpoints {
+		healthy := errs[index] == nil && heads[index]+pool.MaxBlockLag >= bestHead
+
+		endpoint.mu.Lock()
+		wasHealthy := endpoint.healthy
+		endpoint.healthy = healthy
+		endpoint.mu.Unlock()
+
+		switch {
+		case errs[index] != nil:
+			log.LG.Warnf("RPC endpoint %s is unhealthy: %v", endpoint.name, errs[index])
+		case !healthy:
+			log.LG.Warnf("RPC endpoint %s is unhealthy: at block %d, %d blocks behind", endpoint.name, heads[index], bestHead-heads[index])
+		case !wasHealthy:
+			log.LG.Infof("RPC endpoint %s is healthy again at block %d", endpoint.name, heads[index])
+		}
+	}
+}
+
+// ranked returns the connected endpoints, the healthy ones first, each ordered by score.
+func (pool *RPCPool) ranked() []*rpcEndpoint {
+	type rankedEndpoint struct {
+		endpoint *rpcEndpoint
+		healthy  bool
+		score    float64
+	}
+
+	var candidates []rankedEndpoint
+	for _, endpoint := range pool.endpoints {
+		if endpoint.getClient() == nil {
+			continue
+		}
+		healthy, score := endpoint.score()
+		candidates = append(candidates, rankedEndpoint{endpoint: endpoint, healthy: healthy, score: score})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].healthy != candidates[j].healthy {
+			return candidates[i].healthy
+		}
+		return candidates[i].score < candidates[j].score
+	})
+
+	endpoints := make([]*rpcEndpoint, 0, len(candidates))
+	for _, candidate := range candidates {
+		endpoints = append(endpoints, candidate.endpoint)
+	}
+	return endpoints
+}
+
+// isEndpointFailure reports whether an error is caused by the endpoint rather than by the request,
+// in which case the call is failed over to the next endpoint. Errors answered by the node, such as
+// a reverted call, a nonce too low or a log query over too many blocks, would be the same on any endpoint.
+func isEndpointFailure(err error) bool {
+	if isBlockRangeError(err) {
+		return false
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == rpcLimitExceededCode
+	}
+	return true
+}
+
+// call runs fn against the ranked endpoints until one of them answers. A not found answer is also
+// failed over, without penalty, as a lagging endpoint may not know the block or transaction yet.
+func (pool *RPCPool) call(ctx context.Context, method string, fn func(client *ethclient.Client) error) error {
+	lastErr := ErrNoRPCEndpoint
+	for _, endpoint := range pool.ranked() {
+		start := time.Now()
+		err := fn(endpoint.getClient())
+		if err == nil || ctx.Err() != nil {
+			endpoint.record(time.Since(start), nil)
+			return err
+		}
+		if errors.Is(err, ethereum.NotFound) {
+			endpoint.record(time.Since(start), nil)
+			lastErr = err
+			continue
+		}
+		if !isEndpointFailure(err) {
+			endpoint.record(time.Since(start), nil)
+			return err
+		}
+
+		endpoint.record(time.Since(start), err)
+		log.LG.Warnf("RPC %s failed on %s, failing over: %v", method, endpoint.name, err)
+		lastErr = err
+	}
+	return lastErr
+}
+
+// latestBlockNumber asks the connected endpoints for their latest block and returns the highest block reached
+// by at least Quorum of them, so that neither a lagging endpoint nor one reporting a bogus block is trusted alone.
+func (pool *RPCPool) latestBlockNumber(ctx context.Context) (uint64, error) {
+	endpoints := pool.ranked()
+	heads := make([]uint64, len(endpoints))
+	errs := make([]error, len(endpoints))
+
+	var wg sync.WaitGroup
+	for index, endpoint := range endpoints {
+		wg.Add(1)
+		go func(index int, endpoint *rpcEndpoint) {
+			defer wg.Done()
+			readCtx, cancel := context.WithTimeout(ctx, RPCRequestTimeout)
+			defer cancel()
+			start := time.Now()
+			heads[index], errs[index] = endpoint.getClient().BlockNumber(readCtx)
+			endpoint.record(time.Since(start), errs[index])
+		}(index, endpoint)
+	}
+	wg.Wait()
+
+	var answered []uint64
+	for index := range endpoints {
+		if errs[index] == nil {
+			answered = append(answered, heads[index])
+		}
+	}
+	if len(answered) < pool.Quorum {
+		return 0, fmt.Errorf("%w: %d of %d endpoints answered the latest block, %d required", ErrRPCQuorum, len(answered), len(pool.endpoints), pool.Quorum)
+	}
+
+	sort.Slice(answered, func(i, j int) bool { return answered[i] > answered[j] })
+	return answered[pool.Quorum-1], nil
+}
+
+// BlockNumber returns the latest block number agreed on by a quorum of endpoints.
+func (pool *RPCPool) BlockNumber(ctx context.Context) (uint64, error) {
+	if pool.Quorum > 1 {
+		return pool.latestBlockNumber(ctx)
+	}
+
+	var blockNumber uint64
+	err := pool.call(ctx, "BlockNumber", func(client *ethclient.Client) (err error) {
+		blockNumber, err = client.BlockNumber(ctx)
+		return err
+	})
+	return blockNumber, err
+}
+
+// HeaderByNumber returns the header of the given block, or of the latest block agreed on by a quorum of
+// endpoints when number is nil.
+func (pool *RPCPool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if number == nil && pool.Quorum > 1 {
+		latestBlock, err := pool.latestBlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		number = new(big.Int).SetUint64(latestBlock)
+	}
+
+	var header *types.Header
+	err := pool.call(ctx, "HeaderByNumber", func(client *ethclient.Client) (err error) {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+func (pool *RPCPool) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+	err := pool.call(ctx, "HeaderByHash", func(client *ethclient.Client) (err error) {
+		header, err = client.HeaderByHash(ctx, hash)
+		return err
+	})
+	return header, err
+}
+
+func (pool *RPCPool) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	var code []byte
+	err := pool.call(ctx, "CodeAt", func(client *ethclient.Client) (err error) {
+		code, err = client.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (pool *RPCPool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	var code []byte
+	err := pool.call(ctx, "PendingCodeAt", func(client *ethclient.Client) (err error) {
+		code, err = client.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (pool *RPCPool) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	var result []byte
+	err := pool.call(ctx, "CallContract", func(client *ethclient.Client) (err error) {
+		result, err = client.CallContract(ctx, call, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+func (pool *RPCPool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	var nonce uint64
+	err := pool.call(ctx, "PendingNonceAt", func(client *ethclient.Client) (err error) {
+		nonce, err = client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+func (pool *RPCPool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	var gasPrice *big.Int
+	err := pool.call(ctx, "SuggestGasPrice", func(client *ethclient.Client) (err error) {
+		gasPrice, err = client.SuggestGasPrice(ctx)
+		return err
+	})
+	return gasPrice, err
+}
+
+func (pool *RPCPool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	var gasTipCap *big.Int
+	err := pool.call(ctx, "SuggestGasTipCap", func(client *ethclient.Client) (err error) {
+		gasTipCap, err = client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return gasTipCap, err
+}
+
+func (pool *RPCPool) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	var feeHistory *ethereum.FeeHistory
+	err := pool.call(ctx, "FeeHistory", func(client *ethclient.Client) (err error) {
+		feeHistory, err = client.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+		return err
+	})
+	return feeHistory, err
+}
+
+func (pool *RPCPool) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	var gas uint64
+	err := pool.call(ctx, "EstimateGas", func(client *ethclient.Client) (err error) {
+		gas, err = client.EstimateGas(ctx, call)
+		return err
+	})
+	return gas, err
+}
+
+// SendTransaction broadcasts the transaction, failing over like any other call. An endpoint that already
+// knows the transaction means a previous endpoint broadcast it before failing, which counts as a success.
+func (pool *RPCPool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	failedOver := false
+	return pool.call(ctx, "SendTransaction", func(client *ethclient.Client) error {
+		err := client.SendTransaction(ctx, tx)
+		if err != nil && failedOver && strings.Contains(strings.ToLower(err.Error()), "already known") {
+			return nil
+		}
+		failedOver = true
+		return err
+	})
+}
+
+func (pool *RPCPool) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	var tx *types.Transaction
+	var isPending bool
+	err := pool.call(ctx, "TransactionByHash", func(client *ethclient.Client) (err error) {
+		tx, isPending, err = client.TransactionByHash(ctx, hash)
+		return err
+	})
+	return tx, isPending, err
+}
+
+func (pool *RPCPool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var receipt *types.Receipt
+	err := pool.call(ctx, "TransactionReceipt", func(client *ethclient.Client) (err error) {
+		receipt, err = client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+func (pool *RPCPool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	err := pool.call(ctx, "FilterLogs", func(client *ethclient.Client) (err error) {
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+// subscribe runs fn against the ranked WebSocket endpoints until one of them accepts the subscription.
+// The subscription stays bound to that endpoint, the caller subscribes again when it fails.
+func (pool *RPCPool) subscribe(ctx context.Context, fn func(client *ethclient.Client) (ethereum.Subscription, error)) (ethereum.Subscription, error) {
+	var lastErr error = rpc.ErrNotificationsUnsupported
+	for _, endpoint := range pool.ranked() {
+		if !IsWebSocketURL(endpoint.url) {
+			continue
+		}
+
+		start := time.Now()
+		subscription, err := fn(endpoint.getClient())
+		if err == nil || ctx.Err() != nil {
+			endpoint.record(time.Since(start), nil)
+			return subscription, err
+		}
+		endpoint.record(time.Since(start), err)
+		log.LG.Warnf("RPC subscription failed on %s, failing over: %v", endpoint.name, err)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (pool *RPCPool) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return pool.subscribe(ctx, func(client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+func (pool *RPCPool) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return pool.subscribe(ctx, func(client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeNewHead(ctx, ch)
+	})
+}
+
+// Close closes the connections to all endpoints.
+func (pool *RPCPool) Close() {
+	for _, endpoint := range pool.endpoints {
+		if client := endpoint.getClient(); client != nil {
+			client.Close()
+		}
+	}
+}
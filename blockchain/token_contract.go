@@ -0,0 +1,56 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/genefriendway/onchain-handler/contracts/abigen/lifepointtoken"
+)
+
+// TokenContract reads the view functions of the LifePoint token.
+type TokenContract struct {
+	caller *lifepointtoken.LifepointtokenCaller
+}
+
+// NewTokenContract binds the LifePoint token at the given address.
+func NewTokenContract(client EthClient, tokenAddr string) (*TokenContract, error) {
+	caller, err := lifepointtoken.NewLifepointtokenCaller(common.HexToAddress(tokenAddr), client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate ERC20 contract: %w", err)
+	}
+
+	return &TokenContract{
+		caller: caller,
+	}, nil
+}
+
+// BalanceOf returns the token balance of the account, in the smallest unit of the token.
+func (token *TokenContract) BalanceOf(ctx context.Context, account common.Address) (*big.Int, error) {
+	balance, err := token.caller.BalanceOf(&bind.CallOpts{Context: ctx}, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call balanceOf: %w", err)
+	}
+	return balance, nil
+}
+
+// TotalSupply returns the total supply of the token, in its smallest unit.
+func (token *TokenContract) TotalSupply(ctx context.Context) (*big.Int, error) {
+	supply, err := token.caller.TotalSupply(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call totalSupply: %w", err)
+	}
+	return supply, nil
+}
+
+// Allowance returns the amount the spender may still transfer on behalf of the owner, in the smallest unit of the token.
+func (token *TokenContract) Allowance(ctx context.Context, owner, spender common.Address) (*big.Int, error) {
+	allowance, err := token.caller.Allowance(&bind.CallOpts{Context: ctx}, owner, spender)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call allowance: %w", err)
+	}
+	return allowance, nil
+}
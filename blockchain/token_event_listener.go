@@ -0,0 +1,248 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	TokenTransferEvent     = "Transfer"                                 // ERC-20 transfer of the LifePoint token
+	TokenBulkTransferEvent = "BulkTransfer"                             // Emitted by bulkTransfer after the transfers to each recipient
+	LifePointTokenABIFile  = "./contracts/abis/LifePointToken.abi.json" // ABI of the LifePoint token
+	TokenListenerName      = "token"                                    // Name of the TokenEventListener
+)
+
+// TokenEventListener indexes the transfers of the LifePoint token, keeps the balance of each address,
+// and reconciles the bulk transfers against the reward transactions we sent.
+type TokenEventListener struct {
+	Repo         interfaces.TokenRepository
+	TransferRepo interfaces.TransferRepository
+	TokenAddress string
+}
+
+// NewTokenEventListener initializes the token event listener.
+func NewTokenEventListener(repo interfaces.TokenRepository, transferRepo interfaces.TransferRepository, tokenAddress string) *TokenEventListener {
+	return &TokenEventListener{
+		Repo:         repo,
+		TransferRepo: transferRepo,
+		TokenAddress: common.HexToAddress(tokenAddress).Hex(),
+	}
+}
+
+// Definition declares the listener of the token contract. The balances are only correct when the listener
+// starts at the deployment block of the token, which startBlock is expected to return.
+func (listener *TokenEventListener) Definition(startBlock StartBlockFunc) ListenerDefinition {
+	return ListenerDefinition{
+		Name:            TokenListenerName,
+		ABIFile:         LifePointTokenABIFile,
+		ContractAddress: listener.TokenAddress,
+		Handlers: map[string]EventHandler{
+			TokenTransferEvent:     listener.handleTransfer,
+			TokenBulkTransferEvent: listener.handleBulkTransfer,
+		},
+		Rollback:   listener.rollbackTokenTransfers,
+		StartBlock: startBlock,
+	}
+}
+
+// handleTransfer stores a transfer and applies it to the balances of the sender and the recipient.
+// Mints and burns only change the balance of the recipient and the sender respectively.
+func (listener *TokenEventListener) handleTransfer(ctx context.Context, decoded *DecodedEvent) (Event, error) {
+	event := struct {
+		From  common.Address
+		To    common.Address
+		Value *big.Int
+	}{}
+	if err := decoded.Decode(&event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnprocessableLog, err)
+	}
+	vLog := decoded.Log
+
+	blockTime, err := decoded.BlockTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = listener.Repo.CreateTokenTransfer(ctx, model.TokenTransfer{
+		TokenAddress:    listener.TokenAddress,
+		FromAddress:     event.From.Hex(),
+		ToAddress:       event.To.Hex(),
+		Amount:          event.Value.String(),
+		TransactionHash: vLog.TxHash.Hex(),
+		LogIndex:        vLog.Index,
+		BlockNumber:     vLog.BlockNumber,
+		BlockHash:       vLog.BlockHash.Hex(),
+		BlockTimestamp:  blockTime,
+		Status:          constants.TokenTransferSuccess,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	})
+	if err != nil {
+		if isDuplicateTransactionError(err) {
+			// The transfer was already applied to the balances
+			log.LG.Warnf("Duplicate token transfer detected for TxHash %s log %d", vLog.TxHash.Hex(), vLog.Index)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create token transfer: %w", err)
+	}
+
+	if err := listener.applyTransfer(ctx, event.From, event.To, event.Value, vLog.BlockNumber); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// handleBulkTransfer compares a bulk transfer with the reward transfers recorded for its transaction, using the
+// Transfer events that the token emitted before it in the same transaction. Every bulk transfer is published, but
+// those sent by others are not reconciled.
+func (listener *TokenEventListener) handleBulkTransfer(ctx context.Context, decoded *DecodedEvent) (Event, error) {
+	event := struct {
+		Token       common.Address
+		TotalAmount *big.Int
+	}{}
+	if err := decoded.Decode(&event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnprocessableLog, err)
+	}
+	txHash := decoded.Log.TxHash.Hex()
+
+	rewards, err := listener.TransferRepo.GetTransferHistoriesByTxHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward transfers: %w", err)
+	}
+	if len(rewards) == 0 {
+		log.LG.Debugf("Bulk transfer %s was not sent by the reward account", txHash)
+		return decoded, nil
+	}
+
+	transfers, err := listener.Repo.GetTokenTransfersByTxHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token transfers: %w", err)
+	}
+
+	status := constants.ReconciliationMatched
+	if mismatch := reconcileBulkTransfer(rewards, transfers, event.TotalAmount); mismatch != "" {
+		status = constants.ReconciliationMismatched
+		log.LG.Errorf("Bulk transfer %s does not match the recorded reward transfers: %s", txHash, mismatch)
+	}
+
+	if err := listener.TransferRepo.UpdateReconciliationStatusByTxHashes(ctx, []string{txHash}, status); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// rollbackTokenTransfers reverts the balance changes of the transfers from blocks replaced by a chain reorg,
+// marks these transfers as orphaned, and resets the reconciliation of their transactions.
+func (listener *TokenEventListener) rollbackTokenTransfers(ctx context.Context, fromBlock uint64) error {
+	transfers, err := listener.Repo.GetTokenTransfersFromBlock(ctx, listener.TokenAddress, fromBlock)
+	if err != nil {
+		return fmt.Errorf("failed to get token transfers from block %d: %w", fromBlock, err)
+	}
+
+	txHashes := make(map[string]bool)
+	for _, transfer := range transfers {
+		amount, ok := new(big.Int).SetString(transfer.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount %s of token transfer %d", transfer.Amount, transfer.ID)
+		}
+		// Applying the opposite amount reverts the transfer
+		err := listener.applyTransfer(ctx, common.HexToAddress(transfer.FromAddress), common.HexToAddress(transfer.ToAddress), amount.Neg(amount), transfer.BlockNumber)
+		if err != nil {
+			return err
+		}
+		txHashes[transfer.TransactionHash] = true
+	}
+
+	orphaned, err := listener.Repo.MarkTokenTransfersOrphanedFromBlock(ctx, listener.TokenAddress, fromBlock)
+	if err != nil {
+		return fmt.Errorf("failed to mark token transfers from block %d as orphaned: %w", fromBlock, err)
+	}
+
+	hashes := make([]string, 0, len(txHashes))
+	for txHash := range txHashes {
+		hashes = append(hashes, txHash)
+	}
+	if err := listener.TransferRepo.UpdateReconciliationStatusByTxHashes(ctx, hashes, constants.ReconciliationPending); err != nil {
+		return err
+	}
+
+	log.LG.Warnf("Marked %d token transfers from block %d onwards as orphaned", orphaned, fromBlock)
+	return nil
+}
+
+// applyTransfer moves the amount from the balance of the sender to the balance of the recipient.
+func (listener *TokenEventListener) applyTransfer(ctx context.Context, from, to common.Address, amount *big.Int, blockNumber uint64) error {
+	if from != (common.Address{}) {
+		if err := listener.Repo.AddTokenBalance(ctx, listener.TokenAddress, from.Hex(), new(big.Int).Neg(amount), blockNumber); err != nil {
+			return err
+		}
+	}
+	if to != (common.Address{}) {
+		if err := listener.Repo.AddTokenBalance(ctx, listener.TokenAddress, to.Hex(), amount, blockNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileBulkTransfer compares the amount received by each recipient of a bulk transfer with the recorded
+// reward transfers, and the sum of the received amounts with the total emitted by BulkTransfer.
+// It returns a description of the differences, or an empty string when everything matches.
+func reconcileBulkTransfer(rewards []model.TransferHistory, transfers []model.TokenTransfer, totalAmount *big.Int) string {
+	expected := make(map[common.Address]*big.Int)
+	var sender common.Address
+	for _, reward := range rewards {
+		amount, err := toSmallestUnit(reward.TokenAmount, constants.LifePointDecimals)
+		if err != nil {
+			return fmt.Sprintf("invalid recorded amount %s for %s", reward.TokenAmount, reward.RecipientAddress)
+		}
+		recipient := common.HexToAddress(reward.RecipientAddress)
+		if expected[recipient] == nil {
+			expected[recipient] = new(big.Int)
+		}
+		expected[recipient].Add(expected[recipient], amount)
+		sender = common.HexToAddress(reward.RewardAddress)
+	}
+
+	received := make(map[common.Address]*big.Int)
+	total := new(big.Int)
+	for _, transfer := range transfers {
+		if common.HexToAddress(transfer.FromAddress) != sender {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(transfer.Amount, 10)
+		if !ok {
+			return fmt.Sprintf("invalid indexed amount %s for %s", transfer.Amount, transfer.ToAddress)
+		}
+		recipient := common.HexToAddress(transfer.ToAddress)
+		if received[recipient] == nil {
+			received[recipient] = new(big.Int)
+		}
+		received[recipient].Add(received[recipient], amount)
+		total.Add(total, amount)
+	}
+
+	if totalAmount != nil && total.Cmp(totalAmount) != 0 {
+		return fmt.Sprintf("transfers sum up to %s but the bulk transfer total is %s", total, totalAmount)
+	}
+	for recipient, amount := range expected {
+		if received[recipient] == nil || received[recipient].Cmp(amount) != 0 {
+			return fmt.Sprintf("%s received %v instead of %s", recipient.Hex(), received[recipient], amount)
+		}
+	}
+	for recipient, amount := range received {
+		if expected[recipient] == nil {
+			return fmt.Sprintf("%s received %s without a recorded transfer", recipient.Hex(), amount)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,295 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/genefriendway/onchain-handler/conf"
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	util "github.com/genefriendway/onchain-handler/internal/utils/ethereum"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultStuckTxTimeout       = 5 * time.Minute  // Time a transaction may stay pending before its fees are bumped
+	DefaultGasBumpPercent       = 20               // Percentage by which the gas price of a stuck transaction is raised
+	MinGasBumpPercent           = 10               // Minimum price bump nodes accept for a replacement transaction
+	DefaultReplacerPollInterval = 30 * time.Second // Delay between two checks for stuck transactions
+	CancelTxGasLimit            = 21000            // Gas limit of the zero-value self-transfer used to cancel a transaction
+)
+
+// ErrTransactionNotPending is returned when trying to replace a transaction that is not pending anymore.
+var ErrTransactionNotPending = errors.New("transaction is not pending")
+
+// TransactionReplacer speeds up reward transactions that stay pending for too long by re-signing them
+// with the same nonce and a higher gas price, and cancels them on request.
+type TransactionReplacer struct {
+	ETHClient      EthClient
+	Repo           interfaces.TransferRepository
+	ChainID        *big.Int
+	PrivateKey     *ecdsa.PrivateKey
+	StuckTxTimeout time.Duration // Time a transaction may stay pending before its fees are bumped
+	GasBumpPercent uint64        // Percentage by which the gas price of a stuck transaction is raised
+	FeeStrategy    FeeStrategy   // Prices the replacements and enforces the fee caps
+	PollInterval   time.Duration // Delay between two checks for stuck transactions
+}
+
+// NewTransactionReplacer initializes the transaction replacer for the reward account.
+func NewTransactionReplacer(
+	client EthClient,
+	repo interfaces.TransferRepository,
+	feeStrategy FeeStrategy,
+	config *conf.Configuration,
+) (*TransactionReplacer, error) {
+	privateKey, err := util.PrivateKeyFromHex(config.Blockchain.PrivateKeyReward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get private key: %w", err)
+	}
+
+	replacer := &TransactionReplacer{
+		ETHClient:      client,
+		Repo:           repo,
+		ChainID:        new(big.Int).SetUint64(uint64(config.Blockchain.ChainID)),
+		PrivateKey:     privateKey,
+		StuckTxTimeout: config.Blockchain.StuckTxTimeout,
+		GasBumpPercent: config.Blockchain.GasBumpPercent,
+		FeeStrategy:    feeStrategy,
+		PollInterval:   DefaultReplacerPollInterval,
+	}
+	if replacer.StuckTxTimeout == 0 {
+		replacer.StuckTxTimeout = DefaultStuckTxTimeout
+	}
+	if replacer.GasBumpPercent == 0 {
+		replacer.GasBumpPercent = DefaultGasBumpPercent
+	} else if replacer.GasBumpPercent < MinGasBumpPercent {
+		replacer.GasBumpPercent = MinGasBumpPercent
+	}
+
+	return replacer, nil
+}
+
+// Run speeds up stuck transactions periodically until the context is cancelled.
+func (replacer *TransactionReplacer) Run(ctx context.Context) {
+	log.LG.Info("Starting transaction replacer...")
+
+	ticker := time.NewTicker(replacer.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			replacer.speedUpStuckTransactions(ctx)
+		case <-ctx.Done():
+			log.LG.Info("Transaction replacer stopped.")
+			return
+		}
+	}
+}
+
+// speedUpStuckTransactions re-submits every transaction that has been pending for longer than the timeout.
+func (replacer *TransactionReplacer) speedUpStuckTransactions(ctx context.Context) {
+	pendingTransfers, err := replacer.Repo.GetTransferHistoriesByStatus(ctx, constants.TransferStatusPending)
+	if err != nil {
+		log.LG.Errorf("Failed to get pending transfers: %v", err)
+		return
+	}
+
+	stuckTxHashes := make(map[string]bool)
+	for _, transfer := range pendingTransfers {
+		if transfer.TransactionHash != "" && time.Since(transfer.CreatedAt) > replacer.StuckTxTimeout {
+			stuckTxHashes[transfer.TransactionHash] = true
+		}
+	}
+
+	for txHash := range stuckTxHashes {
+		if _, err := replacer.SpeedUp(ctx, txHash); err != nil {
+			log.LG.Warnf("Failed to speed up transaction %s: %v", txHash, err)
+		}
+	}
+}
+
+// SpeedUp re-signs a pending transaction with the same nonce and a bumped gas price, and returns the hash
+// of the replacement. The transfer histories are carried over to the replacement.
+func (replacer *TransactionReplacer) SpeedUp(ctx context.Context, txHash string) (string, error) {
+	tx, isPending, err := replacer.ETHClient.TransactionByHash(ctx, common.HexToHash(txHash))
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			// Left to the transaction tracker, which marks it as dropped
+			return "", ErrTransactionNotPending
+		}
+		return "", fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if !isPending {
+		return "", ErrTransactionNotPending
+	}
+
+	fees, err := replacer.FeeStrategy.BumpFees(ctx, FeesOf(tx), replacer.GasBumpPercent)
+	if err != nil {
+		return "", err
+	}
+
+	replacement, err := replacer.sign(fees.TxData(tx.Nonce(), tx.To(), tx.Value(), tx.Gas(), tx.Data()))
+	if err != nil {
+		return "", err
+	}
+
+	signedReplacement, err := NewSignedTransaction(replacement)
+	if err != nil {
+		return "", err
+	}
+
+	transfers, err := replacer.Repo.GetTransferHistoriesByTxHash(ctx, txHash)
+	if err != nil {
+		return "", err
+	}
+
+	replacements := make([]model.TransferHistory, 0, len(transfers))
+	for _, transfer := range transfers {
+		replacements = append(replacements, model.TransferHistory{
+			RewardAddress:    transfer.RewardAddress,
+			RecipientAddress: transfer.RecipientAddress,
+			TransactionHash:  replacement.Hash().Hex(),
+			TokenAmount:      transfer.TokenAmount,
+			Status:           constants.TransferStatusPending,
+			TxType:           transfer.TxType,
+			Nonce:            replacement.Nonce(),
+			GasPrice:         replacement.GasFeeCap().String(),
+			GasTipCap:        gasTipCapOf(replacement),
+			ReplacesHash:     txHash,
+			BatchID:          transfer.BatchID,
+			BatchIndex:       transfer.BatchIndex,
+			GasLimit:         replacement.Gas(),
+		})
+	}
+
+	// Recorded before the broadcast, so that a mined replacement is never unknown to the transaction tracker
+	if err := replacer.Repo.ReplaceTransferHistories(ctx, txHash, signedReplacement, replacements); err != nil {
+		return "", err
+	}
+	if err := replacer.send(ctx, txHash, replacement); err != nil {
+		return "", err
+	}
+
+	log.LG.Infof("Transaction %s sped up by %s with fee cap %s", txHash, replacement.Hash().Hex(), replacement.GasFeeCap())
+	return replacement.Hash().Hex(), nil
+}
+
+// Cancel replaces a pending transaction with a zero-value transfer to the reward account itself,
+// using the same nonce and a bumped gas price, and returns the hash of the cancelling transaction.
+func (replacer *TransactionReplacer) Cancel(ctx context.Context, txHash string) (string, error) {
+	transfers, err := replacer.Repo.GetTransferHistoriesByTxHash(ctx, txHash)
+	if err != nil {
+		return "", err
+	}
+	if len(transfers) == 0 || transfers[0].Status != constants.TransferStatusPending {
+		return "", ErrTransactionNotPending
+	}
+
+	// Prefer the fees known to the node, and fall back to the recorded ones.
+	currentFees := recordedFees(transfers[0])
+	if tx, isPending, err := replacer.ETHClient.TransactionByHash(ctx, common.HexToHash(txHash)); err == nil {
+		if !isPending {
+			return "", ErrTransactionNotPending
+		}
+		currentFees = FeesOf(tx)
+	}
+
+	fees, err := replacer.FeeStrategy.BumpFees(ctx, currentFees, replacer.GasBumpPercent)
+	if err != nil {
+		return "", err
+	}
+
+	selfAddress := crypto.PubkeyToAddress(replacer.PrivateKey.PublicKey)
+	cancellation, err := replacer.sign(fees.TxData(transfers[0].Nonce, &selfAddress, big.NewInt(0), CancelTxGasLimit, nil))
+	if err != nil {
+		return "", err
+	}
+
+	signedCancellation, err := NewSignedTransaction(cancellation)
+	if err != nil {
+		return "", err
+	}
+
+	cancellationRecord := model.TransferHistory{
+		RewardAddress:    selfAddress.Hex(),
+		RecipientAddress: selfAddress.Hex(),
+		TransactionHash:  cancellation.Hash().Hex(),
+		TokenAmount:      "0",
+		Status:           constants.TransferStatusPending,
+		TxType:           constants.TxTypeCancel,
+		Nonce:            cancellation.Nonce(),
+		GasPrice:         cancellation.GasFeeCap().String(),
+		GasTipCap:        gasTipCapOf(cancellation),
+		ReplacesHash:     txHash,
+		BatchID:          transfers[0].BatchID,
+		BatchIndex:       transfers[0].BatchIndex,
+		GasLimit:         cancellation.Gas(),
+	}
+	if err := replacer.Repo.ReplaceTransferHistories(ctx, txHash, signedCancellation, []model.TransferHistory{cancellationRecord}); err != nil {
+		return "", err
+	}
+	if err := replacer.send(ctx, txHash, cancellation); err != nil {
+		return "", err
+	}
+
+	log.LG.Infof("Transaction %s cancelled by %s with fee cap %s", txHash, cancellation.Hash().Hex(), cancellation.GasFeeCap())
+	return cancellation.Hash().Hex(), nil
+}
+
+// sign signs the transaction with the reward key.
+func (replacer *TransactionReplacer) sign(txData types.TxData) (*types.Transaction, error) {
+	signedTx, err := types.SignNewTx(replacer.PrivateKey, types.LatestSignerForChainID(replacer.ChainID), txData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return signedTx, nil
+}
+
+// send broadcasts a recorded replacement. When the broadcast fails and the node does not know the replacement,
+// the replacement is reverted. Otherwise it may still have reached the node, so it stays pending and the
+// transaction tracker settles it, falling back to the mined transaction it replaced, if any.
+func (replacer *TransactionReplacer) send(ctx context.Context, replacedHash string, signedTx *types.Transaction) error {
+	sendErr := replacer.ETHClient.SendTransaction(ctx, signedTx)
+	if sendErr == nil {
+		return nil
+	}
+
+	if _, _, err := replacer.ETHClient.TransactionByHash(ctx, signedTx.Hash()); errors.Is(err, ethereum.NotFound) {
+		if err := replacer.Repo.RevertTransferReplacement(ctx, replacedHash, signedTx.Hash().Hex()); err != nil {
+			log.LG.Errorf("Failed to revert replacement of transaction %s: %v", replacedHash, err)
+		}
+	}
+	return fmt.Errorf("failed to send transaction %s: %w", signedTx.Hash().Hex(), sendErr)
+}
+
+// recordedFees returns the fees recorded for a transfer when its transaction was sent.
+func recordedFees(transfer model.TransferHistory) *Fees {
+	gasPrice, ok := new(big.Int).SetString(transfer.GasPrice, 10)
+	if !ok {
+		gasPrice = big.NewInt(0)
+	}
+
+	if gasTipCap, ok := new(big.Int).SetString(transfer.GasTipCap, 10); ok {
+		return &Fees{GasFeeCap: gasPrice, GasTipCap: gasTipCap}
+	}
+	return &Fees{GasPrice: gasPrice}
+}
+
+// gasTipCapOf returns the priority fee of an EIP-1559 transaction, or an empty string for other transactions.
+func gasTipCapOf(tx *types.Transaction) string {
+	if tx.Type() != types.DynamicFeeTxType {
+		return ""
+	}
+	return tx.GasTipCap().String()
+}
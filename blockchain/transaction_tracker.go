@@ -0,0 +1,289 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultTrackerPollInterval = 5 * time.Second  // Delay between two rounds of receipt polling
+	DefaultDropTimeout         = 30 * time.Minute // Time after which a transaction unknown to the node is considered dropped
+)
+
+// TransactionTracker polls the receipts of submitted reward transactions and records their outcome.
+type TransactionTracker struct {
+	ETHClient         EthClient
+	Repo              interfaces.TransferRepository
+	UnitOfWork        interfaces.UnitOfWork
+	Webhooks          interfaces.WebhookPublisher // Publishes the outcome of the transactions, nil to disable
+	ConfirmationDepth uint64                      // Number of blocks that must be mined on top of a receipt before it is final
+	PollInterval      time.Duration               // Delay between two rounds of receipt polling
+	DropTimeout       time.Duration               // Time after which a transaction unknown to the node is considered dropped
+}
+
+// NewTransactionTracker initializes the transaction tracker.
+func NewTransactionTracker(
+	client EthClient,
+	repo interfaces.TransferRepository,
+	unitOfWork interfaces.UnitOfWork,
+	confirmationDepth uint64,
+) *TransactionTracker {
+	return &TransactionTracker{
+		ETHClient:         client,
+		Repo:              repo,
+		UnitOfWork:        unitOfWork,
+		ConfirmationDepth: confirmationDepth,
+		PollInterval:      DefaultTrackerPollInterval,
+		DropTimeout:       DefaultDropTimeout,
+	}
+}
+
+// Run checks the pending transactions periodically until the context is cancelled.
+func (tracker *TransactionTracker) Run(ctx context.Context) {
+	log.LG.Info("Starting transaction tracker...")
+
+	ticker := time.NewTicker(tracker.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		tracker.checkPendingTransactions(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.LG.Info("Transaction tracker stopped.")
+			return
+		}
+	}
+}
+
+// checkPendingTransactions updates the status of every pending reward transaction that has a final outcome.
+func (tracker *TransactionTracker) checkPendingTransactions(ctx context.Context) {
+	pendingTransfers, err := tracker.Repo.GetTransferHistoriesByStatus(ctx, constants.TransferStatusPending)
+	if err != nil {
+		log.LG.Errorf("Failed to get pending transfers: %v", err)
+		return
+	}
+	if len(pendingTransfers) == 0 {
+		return
+	}
+
+	// A bulk transfer has one row per recipient, all sharing the same transaction hash.
+	submittedAt := make(map[string]time.Time)
+	for _, transfer := range pendingTransfers {
+		if transfer.TransactionHash == "" {
+			continue
+		}
+		if createdAt, exists := submittedAt[transfer.TransactionHash]; !exists || transfer.CreatedAt.Before(createdAt) {
+			submittedAt[transfer.TransactionHash] = transfer.CreatedAt
+		}
+	}
+
+	latestBlock, err := getLatestBlockNumber(ctx, tracker.ETHClient)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve the latest block number from blockchain: %v", err)
+		return
+	}
+
+	for txHash, createdAt := range submittedAt {
+		if err := tracker.checkTransaction(ctx, common.HexToHash(txHash), createdAt, latestBlock.Uint64()); err != nil {
+			log.LG.Warnf("Failed to check transaction %s: %v", txHash, err)
+		}
+	}
+}
+
+// checkTransaction records the receipt of a transaction once it has reached the confirmation depth.
+func (tracker *TransactionTracker) checkTransaction(ctx context.Context, txHash common.Hash, submittedAt time.Time, latestBlock uint64) error {
+	receipt, err := tracker.ETHClient.TransactionReceipt(ctx, txHash)
+	if errors.Is(err, ethereum.NotFound) {
+		return tracker.checkUnminedTransaction(ctx, txHash, submittedAt, latestBlock)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	// Wait for the confirmation depth, as a reorg could still drop the transaction.
+	if receipt.BlockNumber.Uint64()+tracker.ConfirmationDepth > latestBlock {
+		return nil
+	}
+
+	return tracker.recordReceipt(ctx, txHash, receipt)
+}
+
+// recordReceipt stores the outcome and receipt data of a mined transaction, and publishes the outcome in the same
+// transaction, since the transaction is no longer tracked once its outcome is stored.
+func (tracker *TransactionTracker) recordReceipt(ctx context.Context, txHash common.Hash, receipt *types.Receipt) error {
+	update := model.TransferHistory{
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		GasUsed:     receipt.GasUsed,
+	}
+	if receipt.EffectiveGasPrice != nil {
+		update.EffectiveGasPrice = receipt.EffectiveGasPrice.String()
+	}
+
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		update.Status = constants.TransferStatusConfirmed
+		log.LG.Infof("Transaction %s confirmed in block %d", txHash.Hex(), update.BlockNumber)
+	} else {
+		update.Status = constants.TransferStatusReverted
+		update.ErrorMessage = "Transaction reverted"
+		log.LG.Warnf("Transaction %s reverted in block %d", txHash.Hex(), update.BlockNumber)
+	}
+
+	return tracker.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := tracker.Repo.UpdateTransferHistoriesByTxHash(ctx, txHash.Hex(), update); err != nil {
+			return err
+		}
+		if tracker.Webhooks == nil {
+			return nil
+		}
+		return tracker.publishOutcome(ctx, txHash, update)
+	})
+}
+
+// publishOutcome publishes the confirmation or revert of a reward transaction with its transfers.
+func (tracker *TransactionTracker) publishOutcome(ctx context.Context, txHash common.Hash, update model.TransferHistory) error {
+	transfers, err := tracker.Repo.GetTransferHistoriesByTxHash(ctx, txHash.Hex())
+	if err != nil {
+		return fmt.Errorf("failed to get transfers: %w", err)
+	}
+
+	event := dto.TransferTransactionEventDTO{
+		TransactionHash: txHash.Hex(),
+		BlockNumber:     update.BlockNumber,
+		Status:          update.Status,
+		Transfers:       []dto.TransferHistoryDTO{},
+	}
+	for _, transfer := range transfers {
+		event.Transfers = append(event.Transfers, transfer.ToDto())
+	}
+
+	eventType := constants.WebhookEventTransferConfirmed
+	if update.Status == constants.TransferStatusReverted {
+		eventType = constants.WebhookEventTransferReverted
+	}
+	return tracker.Webhooks.Publish(ctx, eventType, txHash.Hex(), event)
+}
+
+// checkUnminedTransaction marks a transaction as dropped when the node no longer knows about it,
+// either because one of the transactions it replaced was mined instead or because it was evicted.
+// A transaction that was recorded but never reached the node is rebroadcast first.
+func (tracker *TransactionTracker) checkUnminedTransaction(ctx context.Context, txHash common.Hash, submittedAt time.Time, latestBlock uint64) error {
+	_, _, err := tracker.ETHClient.TransactionByHash(ctx, txHash)
+	if err == nil {
+		// Still waiting in the mempool
+		return nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return fmt.Errorf("failed to get transaction: %w", err)
+	}
+
+	minedHash, receipt, err := tracker.findMinedPredecessor(ctx, txHash)
+	if err != nil {
+		return err
+	}
+	if receipt != nil {
+		if receipt.BlockNumber.Uint64()+tracker.ConfirmationDepth > latestBlock {
+			return nil
+		}
+		if err := tracker.recordReceipt(ctx, minedHash, receipt); err != nil {
+			return err
+		}
+
+		log.LG.Warnf("Transaction %s was superseded by the mined transaction %s it replaced", txHash.Hex(), minedHash.Hex())
+		return tracker.Repo.UpdateTransferHistoriesByTxHash(ctx, txHash.Hex(), model.TransferHistory{
+			Status:       constants.TransferStatusDropped,
+			ErrorMessage: fmt.Sprintf("Superseded by mined transaction %s", minedHash.Hex()),
+		})
+	}
+
+	settled, err := tracker.rebroadcast(ctx, txHash)
+	if err != nil || settled {
+		return err
+	}
+
+	if time.Since(submittedAt) < tracker.DropTimeout {
+		return nil
+	}
+
+	log.LG.Warnf("Transaction %s was dropped from the mempool", txHash.Hex())
+	return tracker.Repo.UpdateTransferHistoriesByTxHash(ctx, txHash.Hex(), model.TransferHistory{
+		Status:       constants.TransferStatusDropped,
+		ErrorMessage: "Transaction dropped before being mined",
+	})
+}
+
+// rebroadcast sends a recorded transaction unknown to the node again. It reports whether the transaction was settled,
+// either because it waits in the mempool again or because its nonce was used by another mined transaction, in which
+// case it can never be mined and is marked as dropped.
+func (tracker *TransactionTracker) rebroadcast(ctx context.Context, txHash common.Hash) (bool, error) {
+	signedTx, err := tracker.Repo.GetSignedTransaction(ctx, txHash.Hex())
+	if err != nil || signedTx == nil {
+		return false, err
+	}
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(signedTx.RawTransaction); err != nil {
+		return false, fmt.Errorf("failed to decode signed transaction: %w", err)
+	}
+
+	sendErr := tracker.ETHClient.SendTransaction(ctx, tx)
+	switch {
+	case sendErr == nil || isAlreadyKnownError(sendErr):
+		log.LG.Warnf("Transaction %s was unknown to the node and was rebroadcast", txHash.Hex())
+		return true, nil
+	case isNonceTooLowError(sendErr):
+		// The transaction itself may have been mined since its receipt was checked
+		if _, err := tracker.ETHClient.TransactionReceipt(ctx, txHash); !errors.Is(err, ethereum.NotFound) {
+			return true, err
+		}
+
+		log.LG.Warnf("Nonce %d of transaction %s was used by another transaction", tx.Nonce(), txHash.Hex())
+		return true, tracker.Repo.UpdateTransferHistoriesByTxHash(ctx, txHash.Hex(), model.TransferHistory{
+			Status:       constants.TransferStatusDropped,
+			ErrorMessage: fmt.Sprintf("Nonce %d used by another transaction", tx.Nonce()),
+		})
+	default:
+		log.LG.Warnf("Failed to rebroadcast transaction %s: %v", txHash.Hex(), sendErr)
+		return false, nil
+	}
+}
+
+// findMinedPredecessor walks back the replacement chain of a transaction and returns the first
+// replaced transaction that was mined, if any.
+func (tracker *TransactionTracker) findMinedPredecessor(ctx context.Context, txHash common.Hash) (common.Hash, *types.Receipt, error) {
+	transfers, err := tracker.Repo.GetTransferHistoriesByTxHash(ctx, txHash.Hex())
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+
+	for len(transfers) > 0 && transfers[0].ReplacesHash != "" {
+		replacedHash := common.HexToHash(transfers[0].ReplacesHash)
+
+		receipt, err := tracker.ETHClient.TransactionReceipt(ctx, replacedHash)
+		if err == nil {
+			return replacedHash, receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return common.Hash{}, nil, fmt.Errorf("failed to get receipt of replaced transaction %s: %w", replacedHash.Hex(), err)
+		}
+
+		if transfers, err = tracker.Repo.GetTransferHistoriesByTxHash(ctx, replacedHash.Hex()); err != nil {
+			return common.Hash{}, nil, err
+		}
+	}
+
+	return common.Hash{}, nil, nil
+}
@@ -2,7 +2,6 @@ package blockchain
 
 import (
 	"context"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,12 +9,15 @@ import (
 	"os"
 	"strings"
 
+	"gorm.io/gorm"
+
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/genefriendway/onchain-handler/internal/model"
 )
 
 // loadABI loads and parses the ABI from a JSON file
@@ -38,8 +40,9 @@ func loadABI(path string) (abi.ABI, error) {
 // pollForLogsFromBlock polls logs from a specified block number onwards for the given contract.
 func pollForLogsFromBlock(
 	ctx context.Context,
-	client *ethclient.Client, // Ethereum client
+	client EthClient, // Ethereum client
 	contractAddr common.Address, // Contract address to filter logs
+	eventIDs []common.Hash, // Signatures of the events to keep, all events when empty
 	fromBlock uint64, // Block number to start querying from
 	endBlock uint64,
 ) ([]types.Log, error) {
@@ -49,6 +52,10 @@ func pollForLogsFromBlock(
 		FromBlock: big.NewInt(int64(fromBlock)),   // Start block for querying logs
 		ToBlock:   big.NewInt(int64(endBlock)),    // End block for querying logs
 	}
+	if len(eventIDs) > 0 {
+		// The first topic of a log is the signature of its event
+		query.Topics = [][]common.Hash{eventIDs}
+	}
 
 	// Poll for logs
 	logs, err := client.FilterLogs(ctx, query)
@@ -60,7 +67,7 @@ func pollForLogsFromBlock(
 }
 
 // getLatestBlockNumber retrieves the latest block number from the Ethereum client
-func getLatestBlockNumber(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+func getLatestBlockNumber(ctx context.Context, client EthClient) (*big.Int, error) {
 	header, err := client.HeaderByNumber(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch the latest block header: %w", err)
@@ -68,34 +75,64 @@ func getLatestBlockNumber(ctx context.Context, client *ethclient.Client) (*big.I
 	return header.Number, nil
 }
 
-// parseHexToUint64 parses a hex string to uint64.
-// Handles hex strings that start with "0x" and ignores leading zeros.
-func parseHexToUint64(hexStr string) (uint64, error) {
-	// Ensure the string is lowercase and remove the "0x" prefix if present.
-	hexStr = strings.TrimPrefix(strings.ToLower(hexStr), "0x")
+// FindDeploymentBlock returns the block the contract was deployed in, by searching for the first block at
+// which the contract has code. It requires a node serving the state of past blocks.
+func FindDeploymentBlock(ctx context.Context, client EthClient, contractAddr string) (uint64, error) {
+	address := common.HexToAddress(contractAddr)
+	latestBlock, err := client.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the latest block number: %w", err)
+	}
 
-	// If the string is empty or all zeros, return 0.
-	if len(hexStr) == 0 || strings.TrimLeft(hexStr, "0") == "" {
-		return 0, nil
+	hasCode := func(blockNumber uint64) (bool, error) {
+		code, err := client.CodeAt(ctx, address, new(big.Int).SetUint64(blockNumber))
+		if err != nil {
+			return false, fmt.Errorf("failed to get the code of %s at block %d: %w", address.Hex(), blockNumber, err)
+		}
+		return len(code) > 0, nil
 	}
 
-	// Decode the hex string into bytes.
-	bytes, err := hex.DecodeString(hexStr)
+	deployed, err := hasCode(latestBlock)
 	if err != nil {
-		return 0, fmt.Errorf("invalid hex string: %w", err)
+		return 0, err
+	}
+	if !deployed {
+		return 0, fmt.Errorf("no contract is deployed at %s", address.Hex())
 	}
 
-	// Convert the bytes to uint64. Only the last 8 bytes are relevant for uint64.
-	var value uint64
-	for _, b := range bytes {
-		value = value<<8 + uint64(b)
+	// The contract has code at high and none before low
+	low, high := uint64(0), latestBlock
+	for low < high {
+		middle := low + (high-low)/2
+		deployed, err := hasCode(middle)
+		if err != nil {
+			return 0, err
+		}
+		if deployed {
+			high = middle
+		} else {
+			low = middle + 1
+		}
 	}
+	return high, nil
+}
 
-	return value, nil
+// getBlockHash retrieves the hash of the block with the given number from the Ethereum client
+func getBlockHash(ctx context.Context, client EthClient, blockNumber uint64) (common.Hash, error) {
+	header, err := client.HeaderByNumber(ctx, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to fetch the header of block %d: %w", blockNumber, err)
+	}
+	return header.Hash(), nil
 }
 
 // isDuplicateTransactionError checks if the error is due to a unique constraint violation (e.g., duplicate transaction hash).
 func isDuplicateTransactionError(err error) bool {
+	// The repositories report an upsert that did not touch any existing row as a duplicated key
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+
 	var pqErr *pgconn.PgError
 	// Check if the error is a PostgreSQL error and has a unique violation code
 	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
@@ -106,3 +143,49 @@ func isDuplicateTransactionError(err error) bool {
 	}
 	return false
 }
+
+// isNonceError checks if the error returned when sending a transaction is due to a nonce that does not match the account state.
+func isNonceError(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "nonce too low") || strings.Contains(message, "nonce too high")
+}
+
+// isNonceTooLowError checks if the error returned when sending a transaction is due to a nonce already used by a mined transaction.
+func isNonceTooLowError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+// isAlreadyKnownError checks if the error returned when sending a transaction is due to the transaction being in the mempool already.
+func isAlreadyKnownError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "already known")
+}
+
+// NewSignedTransaction encodes a signed transaction to be recorded before its broadcast.
+func NewSignedTransaction(tx *types.Transaction) (*model.SignedTransaction, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction %s: %w", tx.Hash().Hex(), err)
+	}
+	return &model.SignedTransaction{TransactionHash: tx.Hash().Hex(), RawTransaction: raw}, nil
+}
+
+// isRevertError checks if the error reports that the call reverted during execution
+func isRevertError(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
+
+// toSmallestUnit converts a decimal token amount, e.g. "1.5" or "100.000000000000000000", into the smallest unit
+// of a token with the given number of decimals. Digits beyond the decimals of the token are rejected.
+func toSmallestUnit(amount string, decimals int) (*big.Int, error) {
+	whole, fraction, _ := strings.Cut(amount, ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("amount %s has more than %d decimals", amount, decimals)
+	}
+
+	value, ok := new(big.Int).SetString(whole+fraction+strings.Repeat("0", decimals-len(fraction)), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %s", amount)
+	}
+	return value, nil
+}
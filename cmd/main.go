@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/genefriendway/onchain-handler/conf"
 	_ "github.com/genefriendway/onchain-handler/docs"
 	app "github.com/genefriendway/onchain-handler/internal"
@@ -8,5 +10,9 @@ import (
 
 func main() {
 	config := conf.GetConfiguration()
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		app.RunBackfill(config, os.Args[2:])
+		return
+	}
 	app.RunApp(config)
 }
@@ -3,14 +3,18 @@ package conf
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
 
 type RedisConfiguration struct {
-	RedisAddress string `mapstructure:"REDIS_ADDRESS"`
-	RedisTtl     string `mapstructure:"REDIS_TTL"`
+	RedisAddress      string `mapstructure:"REDIS_ADDRESS"`
+	RedisTtl          string `mapstructure:"REDIS_TTL"`
+	RedisStreamPrefix string `mapstructure:"REDIS_STREAM_PREFIX"`  // Prefix of the streams of the redis event sink
+	RedisStreamMaxLen int64  `mapstructure:"REDIS_STREAM_MAX_LEN"` // Approximate number of entries kept per stream
 }
 
 type DatabaseConfiguration struct {
@@ -22,13 +26,31 @@ type DatabaseConfiguration struct {
 }
 
 type BlockchainConfiguration struct {
-	RpcUrl                    string `mapstructure:"RPC_URL"`
-	ChainID                   uint32 `mapstructure:"CHAIN_ID"`
-	PrivateKeyReward          string `mapstructure:"PRIVATE_KEY_REWARD"`
-	RewardAddress             string `mapstructure:"REWARD_ADDRESS"`
-	LifePointAddress          string `mapstructure:"LIFE_POINT_ADDRESS"`
-	MembershipContractAddress string `mapstructure:"MEMBERSHIP_CONTRACT_ADDRESS"`
-	StartBlockListener        uint64 `mapstructure:"START_BLOCK_LISTENER"`
+	RpcUrl                    string        `mapstructure:"RPC_URL"`
+	RpcUrls                   []string      `mapstructure:"RPC_URLS"`                  // RPC endpoints to spread the calls over, e.g. "https://a,wss://b", RPC_URL is used when empty
+	RpcQuorum                 int           `mapstructure:"RPC_QUORUM"`                // Number of endpoints that must have reached a block for it to be the latest one
+	RpcHealthCheckInterval    time.Duration `mapstructure:"RPC_HEALTH_CHECK_INTERVAL"` // Delay between two health checks of the RPC endpoints, e.g. "15s"
+	RpcMaxBlockLag            uint64        `mapstructure:"RPC_MAX_BLOCK_LAG"`         // Number of blocks an RPC endpoint may lag behind the others and stay healthy
+	ChainID                   uint32        `mapstructure:"CHAIN_ID"`
+	PrivateKeyReward          string        `mapstructure:"PRIVATE_KEY_REWARD"`
+	RewardAddress             string        `mapstructure:"REWARD_ADDRESS"`
+	LifePointAddress          string        `mapstructure:"LIFE_POINT_ADDRESS"`
+	MembershipContractAddress string        `mapstructure:"MEMBERSHIP_CONTRACT_ADDRESS"`
+	StartBlockListener        uint64        `mapstructure:"START_BLOCK_LISTENER"`
+	TokenStartBlockListener   uint64        `mapstructure:"TOKEN_START_BLOCK_LISTENER"` // First block indexed by the token listener, the deployment block of the token when 0
+	MinBlockRange             uint64        `mapstructure:"LOG_MIN_BLOCK_RANGE"`        // Smallest number of blocks the listeners query logs for at once
+	MaxBlockRange             uint64        `mapstructure:"LOG_MAX_BLOCK_RANGE"`        // Largest number of blocks the listeners query logs for at once, e.g. the provider's limit
+	ConfirmationDepth         uint64        `mapstructure:"CONFIRMATION_DEPTH"`         // Number of blocks an event must be buried under before it is indexed
+	StuckTxTimeout            time.Duration `mapstructure:"STUCK_TX_TIMEOUT"`           // Time a transaction may stay pending before its fees are bumped, e.g. "5m"
+	GasBumpPercent            uint64        `mapstructure:"GAS_BUMP_PERCENT"`           // Percentage by which the gas price of a stuck transaction is raised
+	MaxGasPriceGwei           uint64        `mapstructure:"MAX_GAS_PRICE_GWEI"`         // Cap on the gas price or EIP-1559 fee cap, 0 for no cap
+	FeeStrategy               string        `mapstructure:"FEE_STRATEGY"`               // "auto", "eip1559" or "legacy"
+	GasPriceMultiplier        float64       `mapstructure:"GAS_PRICE_MULTIPLIER"`       // Multiplier applied to the suggested legacy gas price
+	BaseFeeMultiplier         float64       `mapstructure:"BASE_FEE_MULTIPLIER"`        // Multiplier applied to the next base fee for the EIP-1559 fee cap
+	TipMultiplier             float64       `mapstructure:"TIP_MULTIPLIER"`             // Multiplier applied to the suggested EIP-1559 priority fee
+	MaxPriorityFeeGwei        uint64        `mapstructure:"MAX_PRIORITY_FEE_GWEI"`      // Cap on the EIP-1559 priority fee, 0 for no cap
+	MaxGasPerTx               uint64        `mapstructure:"MAX_GAS_PER_TX"`             // Gas budget of a single bulk transfer, lowered to the block gas limit if needed
+	GasLimitBufferPercent     uint64        `mapstructure:"GAS_LIMIT_BUFFER_PERCENT"`   // Percentage added on top of the gas estimate of a bulk transfer
 }
 
 type Configuration struct {
@@ -38,6 +60,21 @@ type Configuration struct {
 	AppName    string                  `mapstructure:"APP_NAME"`
 	AppPort    uint32                  `mapstructure:"APP_PORT"`
 	Env        string                  `mapstructure:"ENV"`
+	AdminKey   string                  `mapstructure:"ADMIN_API_KEY"` // Key required by the admin endpoints, which are disabled when empty
+
+	TransferWorkers        int `mapstructure:"TRANSFER_WORKERS"`          // Number of workers processing transfer jobs
+	TransferJobMaxAttempts int `mapstructure:"TRANSFER_JOB_MAX_ATTEMPTS"` // Attempts after which a transfer job is marked as failed
+
+	MembershipExpiryInterval  time.Duration   `mapstructure:"MEMBERSHIP_EXPIRY_INTERVAL"`   // Delay between two checks of the memberships expiring, e.g. "5m"
+	MembershipExpiryLeadTimes []time.Duration `mapstructure:"MEMBERSHIP_EXPIRY_LEAD_TIMES"` // Times before expiry at which users are notified, e.g. "168h,24h"
+	MembershipWebhookURL      string          `mapstructure:"MEMBERSHIP_WEBHOOK_URL"`       // URL the membership notifications are posted to, logged only when empty
+
+	WebhookWorkers     int `mapstructure:"WEBHOOK_WORKERS"`      // Number of workers delivering webhooks
+	WebhookMaxAttempts int `mapstructure:"WEBHOOK_MAX_ATTEMPTS"` // Attempts after which a webhook delivery is dead-lettered
+
+	EventSinks []string `mapstructure:"EVENT_SINKS"` // Sinks the indexed events are published to: "postgres", "redis" and/or "webhook"
+
+	TokenCacheTTL time.Duration `mapstructure:"TOKEN_CACHE_TTL"` // Time the token balances, supply and allowances read from the chain are cached in Redis, e.g. "15s"
 }
 
 var configuration Configuration
@@ -68,6 +105,20 @@ func GetConfiguration() *Configuration {
 	return &configuration
 }
 
+// RpcEndpoints returns the RPC endpoints to connect to, RPC_URLS or else RPC_URL.
+func (config BlockchainConfiguration) RpcEndpoints() []string {
+	var endpoints []string
+	for _, rpcURL := range config.RpcUrls {
+		if rpcURL = strings.TrimSpace(rpcURL); rpcURL != "" {
+			endpoints = append(endpoints, rpcURL)
+		}
+	}
+	if len(endpoints) == 0 && config.RpcUrl != "" {
+		endpoints = append(endpoints, config.RpcUrl)
+	}
+	return endpoints
+}
+
 func GetRedisConnectionURL() string {
 	return configuration.Redis.RedisAddress
 }
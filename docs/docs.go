@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/membership/events": {
+        "/api/v1/admin/listeners": {
             "get": {
-                "description": "This endpoint fetches a list of membership events based on the provided comma-separated list of order IDs.",
+                "description": "This admin endpoint returns every event listener with the next block it processes, the chain head, its lag behind the chain head and the last error that held it back.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,30 +25,75 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "membership"
+                    "listener"
                 ],
-                "summary": "Retrieve membership events by order IDs",
+                "summary": "List event listeners",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Comma-separated list of order IDs",
-                        "name": "orderIds",
-                        "in": "query",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful retrieval of membership events",
+                        "description": "Event listeners",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/dto.MembershipEventDTO"
+                                "$ref": "#/definitions/dto.ListenerStatusDTO"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Invalid Order IDs or missing Order IDs",
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/listeners/{name}/pause": {
+            "post": {
+                "description": "This admin endpoint stops an event listener from processing blocks once the block chunk being processed is done. The listener stays paused until it is resumed or the service restarts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "listener"
+                ],
+                "summary": "Pause an event listener",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Listener name, e.g. membership or token",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Listener paused",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListenerStatusDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Listener not found",
                         "schema": {
                             "$ref": "#/definitions/util.GeneralError"
                         }
@@ -62,9 +107,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/transfer": {
+        "/api/v1/admin/listeners/{name}/reset": {
             "post": {
-                "description": "This endpoint allows the distribution of tokens to multiple recipients. It accepts a list of transfer requests, validates the payload, and processes the token transfers based on the transaction type.",
+                "description": "This admin endpoint makes an event listener start over from its configured start block, forgetting its block cursor, the block hashes kept for reorg detection and the events kept for retraction. The reset is applied before the next block chunk, even while the listener is paused, and is reported as pending until then. Events indexed again are skipped, and only the events that were missed are indexed and published.",
                 "consumes": [
                     "application/json"
                 ],
@@ -72,82 +117,1646 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "transfer"
+                    "listener"
                 ],
-                "summary": "Distribute tokens to recipients",
+                "summary": "Reset an event listener",
                 "parameters": [
                     {
-                        "description": "List of transfer requests. Each request must include recipient address and transaction type.",
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Listener name, e.g. membership or token",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Reset accepted",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListenerStatusDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Listener not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/listeners/{name}/resume": {
+            "post": {
+                "description": "This admin endpoint lets a paused event listener process blocks again, from where it stopped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "listener"
+                ],
+                "summary": "Resume an event listener",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Listener name, e.g. membership or token",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Listener resumed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListenerStatusDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Listener not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/listeners/{name}/rewind": {
+            "post": {
+                "description": "This admin endpoint makes an event listener process the blocks again from the given block, which must not be after the next block it processes. The rewind is applied before the next block chunk, even while the listener is paused, and is reported as pending until then. Events indexed again are skipped, and only the events that were missed are indexed and published.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "listener"
+                ],
+                "summary": "Rewind an event listener",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Listener name, e.g. membership or token",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Block to process again from",
                         "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/dto.TransferTokenPayloadDTO"
-                            }
+                            "$ref": "#/definitions/dto.RewindListenerPayloadDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Rewind accepted",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ListenerStatusDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payload or block",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "404": {
+                        "description": "Listener not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/admin/transfer/transactions/{txHash}/cancel": {
+            "post": {
+                "description": "This admin endpoint replaces a pending reward transaction with a zero-value transfer to the reward account, using the same nonce and a bumped gas price.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Cancel a pending transaction",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction hash",
+                        "name": "txHash",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Success response: {\\\"success\\\": true}",
+                        "description": "Hash of the cancelling transaction: {\\\"transaction_hash\\\": \\\"0x...\\\"}",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "boolean"
+                                "type": "string"
                             }
                         }
                     },
                     "400": {
-                        "description": "Invalid payload or invalid recipient address/transaction type",
+                        "description": "Invalid transaction hash",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "409": {
+                        "description": "Transaction is not pending",
                         "schema": {
                             "$ref": "#/definitions/util.GeneralError"
                         }
                     },
                     "500": {
-                        "description": "Internal server error, failed to distribute tokens",
+                        "description": "Internal server error",
                         "schema": {
                             "$ref": "#/definitions/util.GeneralError"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.MembershipEventDTO": {
-            "type": "object",
-            "properties": {
-                "amount": {
-                    "type": "string"
-                },
-                "end_duration": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "order_id": {
-                    "type": "integer"
-                },
-                "status": {
-                    "type": "integer"
-                },
-                "transaction_hash": {
-                    "type": "string"
-                },
-                "user_address": {
-                    "type": "string"
+        },
+        "/api/v1/admin/webhooks/deliveries": {
+            "get": {
+                "description": "This admin endpoint returns the webhook deliveries matching the given filters, newest first. Statuses are 0 pending, 1 delivering, 2 delivered and 3 dead letter.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size, between 4 and 50",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event type",
+                        "name": "event_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Status (0 pending, 1 delivering, 2 delivered, 3 dead letter)",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook deliveries",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/dto.PaginationDTOResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dto.WebhookDeliveryDTO"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
                 }
             }
         },
-        "dto.TransferTokenPayloadDTO": {
-            "type": "object",
-            "properties": {
-                "recipient_address": {
-                    "type": "string"
-                },
+        "/api/v1/admin/webhooks/deliveries/{id}/replay": {
+            "post": {
+                "description": "This admin endpoint queues a delivery again with a fresh set of attempts, typically a dead-lettered one. The replayed request carries the same event ID, so that subscribers can ignore it if they already processed the event.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Replay a webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delivery queued again",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebhookDeliveryDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid delivery ID",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "404": {
+                        "description": "Delivery not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "409": {
+                        "description": "Delivery is being delivered or was superseded",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/webhooks/subscriptions": {
+            "get": {
+                "description": "This admin endpoint returns every webhook subscription, active or not, without their secrets.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List webhook subscribers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook subscriptions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.WebhookSubscriptionDTO"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "This admin endpoint registers a URL receiving the events of a type (MembershipPurchased, TransferConfirmed or TransferReverted). Each request carries the X-Webhook-Signature header, \"sha256=\" followed by the hex HMAC-SHA256 of \"\u003cX-Webhook-Timestamp\u003e.\u003cbody\u003e\" keyed with the subscription secret. The secret is generated when none is given, and is only returned by this endpoint.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Register a webhook subscriber",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Event type, URL and optional secret of the subscriber",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateWebhookSubscriptionPayloadDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Registered subscription, including its secret",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebhookSubscriptionDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payload or unknown event type",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/webhooks/subscriptions/{id}": {
+            "delete": {
+                "description": "This admin endpoint stops the deliveries to a subscriber. Its delivery history is kept, and its queued deliveries are dead-lettered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Deactivate a webhook subscriber",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Subscription deactivated"
+                    },
+                    "400": {
+                        "description": "Invalid subscription ID",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "404": {
+                        "description": "Active subscription not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/membership/events": {
+            "get": {
+                "description": "This endpoint fetches a list of membership events based on the provided comma-separated list of order IDs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "membership"
+                ],
+                "summary": "Retrieve membership events by order IDs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Comma-separated list of order IDs",
+                        "name": "orderIds",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of membership events",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.MembershipEventDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Order IDs or missing Order IDs",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/membership/users/{address}": {
+            "get": {
+                "description": "This endpoint returns whether the user's membership is currently active and when it expires, computed from the indexed purchases with renewals stacked, along with the purchase history. With verify=true, the status is cross-checked against the membership contract.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "membership"
+                ],
+                "summary": "Retrieve the membership status of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User address",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Cross-check the status against the membership contract",
+                        "name": "verify",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of the membership status",
+                        "schema": {
+                            "$ref": "#/definitions/dto.MembershipStatusDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user address",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/allowance": {
+            "get": {
+                "description": "This endpoint returns the amount of LifePoint the spender may still transfer on behalf of the owner, in the smallest unit of the token, as read from the token contract. Allowances are cached for a few seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve a token allowance",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Owner address",
+                        "name": "owner",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Spender address",
+                        "name": "spender",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Allowance",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenAllowanceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid owner or spender address",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/balances/{address}": {
+            "get": {
+                "description": "This endpoint returns the LifePoint balance of an address in the smallest unit of the token, as read from the token contract. Balances are cached for a few seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve the on-chain token balance of an address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Address",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "On-chain balance",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenOnChainBalanceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid address",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/ledger/{address}": {
+            "get": {
+                "description": "This endpoint returns the LifePoint balance of an address in the smallest unit of the token, computed from the Transfer events indexed so far, and the block of the last transfer applied to it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve the indexed token balance of an address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Address",
+                        "name": "address",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Indexed balance",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenBalanceDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid address",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/membership/balance": {
+            "get": {
+                "description": "This endpoint returns the LifePoint balance of the membership contract, which receives the membership payments, as read from the token contract. Balances are cached for a few seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve the token balance of the membership contract",
+                "responses": {
+                    "200": {
+                        "description": "On-chain balance",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenOnChainBalanceDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/reward/balance": {
+            "get": {
+                "description": "This endpoint returns the LifePoint balance of the wallet the rewards are paid from, as read from the token contract. Balances are cached for a few seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve the token balance of the reward wallet",
+                "responses": {
+                    "200": {
+                        "description": "On-chain balance",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenOnChainBalanceDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/supply": {
+            "get": {
+                "description": "This endpoint returns the total supply of LifePoint in the smallest unit of the token, as read from the token contract. The supply is cached for a few seconds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve the token total supply",
+                "responses": {
+                    "200": {
+                        "description": "Total supply",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TokenSupplyDTO"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token/transfers": {
+            "get": {
+                "description": "This endpoint returns the LifePoint transfers indexed from the Transfer events of the token, newest first, optionally only those from or to an address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Retrieve indexed token transfers",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size, between 4 and 50",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sender or recipient address",
+                        "name": "address",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Indexed transfers",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/dto.PaginationDTOResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dto.TokenTransferDTO"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid address",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfer": {
+            "post": {
+                "description": "This endpoint allows the distribution of tokens to multiple recipients. It accepts a list of transfer requests, validates the payload, and queues a transfer job that distributes the tokens in the background. The job status can be followed with /api/v1/transfer/jobs/{id}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Distribute tokens to recipients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key identifying the request. A retry with the same key returns the original job instead of queueing a new one.",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "List of transfer requests. Each request must include recipient address and transaction type.",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.TransferTokenPayloadDTO"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Queued transfer job",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TransferJobDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payload or invalid recipient address/transaction type",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "422": {
+                        "description": "The idempotency key was already used for another payload",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error, failed to queue the transfer job",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfer/jobs/{id}": {
+            "get": {
+                "description": "This endpoint returns the status of a transfer job (0 queued, 1 processing, 2 completed, 3 failed), its attempts and the transfers created for it so far.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Retrieve a transfer job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transfer job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of the transfer job",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TransferJobDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid job ID",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer job not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfer/transactions/{txHash}": {
+            "get": {
+                "description": "This endpoint returns the transfers sent in the given reward transaction, including their confirmation status (-1 failed, 0 pending, 1 confirmed, 2 reverted, 3 dropped) and receipt data.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Retrieve transfers by transaction hash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Transaction hash",
+                        "name": "txHash",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of transfers",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.TransferHistoryDTO"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transaction hash",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transaction not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfers": {
+            "get": {
+                "description": "This endpoint returns the transfers matching the given filters, newest first. Dates are RFC 3339 timestamps or YYYY-MM-DD dates.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Retrieve transfer history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size, between 4 and 50",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Recipient address",
+                        "name": "recipient_address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction type (PURCHASE, COMMISSION or CANCEL)",
+                        "name": "tx_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Status (-1 failed, 0 pending, 1 confirmed, 2 reverted, 3 dropped, 4 replaced)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Transaction hash",
+                        "name": "tx_hash",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transfers created at or after this date",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only transfers created before this date",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of transfers",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/dto.PaginationDTOResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/dto.TransferHistoryDTO"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/transfers/{id}": {
+            "get": {
+                "description": "This endpoint returns the transfer with the given ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "transfer"
+                ],
+                "summary": "Retrieve a transfer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Transfer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful retrieval of the transfer",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TransferHistoryDTO"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid transfer ID",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "404": {
+                        "description": "Transfer not found",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/util.GeneralError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.CreateWebhookSubscriptionPayloadDTO": {
+            "type": "object",
+            "required": [
+                "event_type",
+                "url"
+            ],
+            "properties": {
+                "event_type": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Generated when empty",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ListenerStatusDTO": {
+            "type": "object",
+            "properties": {
+                "chain_head": {
+                    "type": "integer"
+                },
+                "chain_id": {
+                    "type": "integer"
+                },
+                "contract_address": {
+                    "type": "string"
+                },
+                "current_block": {
+                    "description": "Next block to process",
+                    "type": "integer"
+                },
+                "event_names": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "lag": {
+                    "description": "Blocks mined after the last processed block, including the unconfirmed ones",
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_error_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "pending_reset": {
+                    "description": "Whether the pending rewind also forgets the state of the listener",
+                    "type": "boolean"
+                },
+                "pending_rewind": {
+                    "type": "integer"
+                },
+                "running": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.MembershipEventDTO": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "block_number": {
+                    "type": "integer"
+                },
+                "block_timestamp": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "integer"
+                },
+                "end_duration": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "log_index": {
+                    "type": "integer"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "transaction_hash": {
+                    "type": "string"
+                },
+                "user_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MembershipStatusDTO": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "description": "Expiry with renewals stacked, nil if the user never purchased a membership",
+                    "type": "string"
+                },
+                "on_chain": {
+                    "$ref": "#/definitions/dto.OnChainMembershipDTO"
+                },
+                "purchases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.MembershipEventDTO"
+                    }
+                },
+                "user_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OnChainMembershipDTO": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "consistent": {
+                    "description": "Whether the contract agrees with the indexed purchases on the membership being active",
+                    "type": "boolean"
+                },
+                "expiration": {
+                    "type": "string"
+                },
+                "is_member": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.PaginationDTOResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "next_page": {
+                    "description": "0 when this is the last page",
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Number of items matching the query across all pages",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.RewindListenerPayloadDTO": {
+            "type": "object",
+            "required": [
+                "block"
+            ],
+            "properties": {
+                "block": {
+                    "description": "Block to process again from",
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.TokenAllowanceDTO": {
+            "type": "object",
+            "properties": {
+                "allowance": {
+                    "description": "In the smallest unit of the token",
+                    "type": "string"
+                },
+                "fetched_at": {
+                    "type": "string"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "spender": {
+                    "type": "string"
+                },
+                "token_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TokenBalanceDTO": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "balance": {
+                    "description": "In the smallest unit of the token",
+                    "type": "string"
+                },
+                "last_block": {
+                    "description": "Block of the last transfer applied to the balance, 0 if there was none",
+                    "type": "integer"
+                },
+                "token_address": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TokenOnChainBalanceDTO": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "balance": {
+                    "description": "In the smallest unit of the token",
+                    "type": "string"
+                },
+                "fetched_at": {
+                    "description": "When the balance was read from the chain, it may be served from cache for a few seconds",
+                    "type": "string"
+                },
+                "token_address": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TokenSupplyDTO": {
+            "type": "object",
+            "properties": {
+                "fetched_at": {
+                    "type": "string"
+                },
+                "token_address": {
+                    "type": "string"
+                },
+                "total_supply": {
+                    "description": "In the smallest unit of the token",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TokenTransferDTO": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "In the smallest unit of the token",
+                    "type": "string"
+                },
+                "block_number": {
+                    "type": "integer"
+                },
+                "block_timestamp": {
+                    "type": "string"
+                },
+                "from_address": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "log_index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "to_address": {
+                    "type": "string"
+                },
+                "token_address": {
+                    "type": "string"
+                },
+                "transaction_hash": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TransferHistoryDTO": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string"
+                },
+                "batch_index": {
+                    "type": "integer"
+                },
+                "block_number": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_gas_price": {
+                    "type": "string"
+                },
+                "error_message": {
+                    "type": "string"
+                },
+                "gas_limit": {
+                    "type": "integer"
+                },
+                "gas_price": {
+                    "type": "string"
+                },
+                "gas_tip_cap": {
+                    "type": "string"
+                },
+                "gas_used": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "nonce": {
+                    "type": "integer"
+                },
+                "recipient_address": {
+                    "type": "string"
+                },
+                "reconciliation_status": {
+                    "description": "0 not reconciled yet, 1 matched, 2 mismatched with the BulkTransfer event",
+                    "type": "integer"
+                },
+                "replaced_by_hash": {
+                    "type": "string"
+                },
+                "replaces_hash": {
+                    "type": "string"
+                },
+                "reward_address": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "token_amount": {
+                    "type": "string"
+                },
+                "transaction_hash": {
+                    "type": "string"
+                },
+                "tx_type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TransferJobDTO": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "replayed": {
+                    "description": "True when the job was created by an earlier request with the same idempotency key",
+                    "type": "boolean"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "transfers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.TransferHistoryDTO"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.TransferTokenPayloadDTO": {
+            "type": "object",
+            "properties": {
+                "recipient_address": {
+                    "type": "string"
+                },
                 "token_amount": {
                     "type": "string"
                 },
@@ -156,6 +1765,83 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.WebhookDeliveryDTO": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "removed": {
+                    "type": "boolean"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "superseded": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.WebhookSubscriptionDTO": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Only returned when the subscription is created",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "util.GeneralError": {
             "type": "object",
             "properties": {
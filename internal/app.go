@@ -9,12 +9,12 @@ import (
 
 	"gorm.io/gorm/logger"
 
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
+	"github.com/genefriendway/onchain-handler/blockchain"
 	"github.com/genefriendway/onchain-handler/conf"
 	"github.com/genefriendway/onchain-handler/conf/database"
 	"github.com/genefriendway/onchain-handler/internal/middleware"
@@ -38,15 +38,16 @@ func RunApp(config *conf.Configuration) {
 
 	db := database.DBConnWithLoglevel(logger.Info)
 
+	// Create a context to handle shutdown signals
+	ctx, cancel := context.WithCancel(context.Background())
+
 	// SECTION: Init eth client
-	ethClient, err := ethclient.Dial(config.Blockchain.RpcUrl)
+	ethClient, err := blockchain.NewRPCPool(ctx, config.Blockchain)
 	if err != nil {
 		log.LG.Fatalf("failed to connect to eth client: %v", err)
 	}
 	defer ethClient.Close()
-
-	// Create a context to handle shutdown signals
-	ctx, cancel := context.WithCancel(context.Background())
+	go ethClient.Run(ctx)
 
 	// SECTION: Register routes with context
 	routeV1.RegisterRoutes(r, config, db, ethClient, ctx)
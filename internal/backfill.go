@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"gorm.io/gorm/logger"
+
+	"github.com/rs/zerolog"
+
+	"github.com/genefriendway/onchain-handler/blockchain"
+	"github.com/genefriendway/onchain-handler/conf"
+	"github.com/genefriendway/onchain-handler/conf/database"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
+	"github.com/genefriendway/onchain-handler/internal/module/webhook"
+	routeV1 "github.com/genefriendway/onchain-handler/internal/route"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// RunBackfill scans a block range again for the events of a listener, e.g. to recover events missed by the
+// live listener, without moving its cursor. Usage:
+//
+//	onchain-handler backfill -listener membership -from 1000 [-to 2000] [-workers 4]
+//
+// The range ends at the latest confirmed block when -to is omitted. Webhooks of the recovered events are
+// queued for the webhook workers of the running server.
+func RunBackfill(config *conf.Configuration, args []string) {
+	log.LG = log.NewZerologLogger(os.Stdout, zerolog.InfoLevel)
+
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	listenerName := flags.String("listener", "", fmt.Sprintf("Listener to backfill: %q or %q", blockchain.MembershipListenerName, blockchain.TokenListenerName))
+	fromBlock := flags.Uint64("from", 0, "First block to scan, required")
+	toBlock := flags.Uint64("to", 0, "Last block to scan, the latest confirmed block when omitted")
+	workers := flags.Int("workers", blockchain.DefaultBackfillWorkers, "Number of block ranges scanned in parallel")
+	_ = flags.Parse(args)
+
+	// Block 0 is a valid bound, so the flags are told apart from their zero value by whether they were set
+	setFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+	if *listenerName == "" || !setFlags["from"] {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// Stop the backfill on shutdown signals
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	db := database.DBConnWithLoglevel(logger.Warn)
+
+	ethClient, err := blockchain.NewRPCPool(ctx, config.Blockchain)
+	if err != nil {
+		log.LG.Fatalf("failed to connect to eth client: %v", err)
+	}
+	defer ethClient.Close()
+	go ethClient.Run(ctx)
+
+	webhookUCase := webhook.NewWebhookUCase(webhook.NewWebhookRepository(db), config.WebhookMaxAttempts)
+	listenerRegistry, err := routeV1.NewListenerRegistry(config, db, ethClient, unitofwork.NewUnitOfWork(db), webhookUCase)
+	if err != nil {
+		log.LG.Fatalf("failed to initialize event listeners: %v", err)
+	}
+
+	listener := listenerRegistry.Listener(strings.ToLower(*listenerName))
+	if listener == nil {
+		log.LG.Fatalf("unknown listener %q", *listenerName)
+	}
+
+	if !setFlags["to"] {
+		latestBlock, err := ethClient.BlockNumber(ctx)
+		if err != nil {
+			log.LG.Fatalf("failed to retrieve the latest block number: %v", err)
+		}
+		if latestBlock < config.Blockchain.ConfirmationDepth {
+			log.LG.Fatalf("no confirmed block to backfill yet")
+		}
+		*toBlock = latestBlock - config.Blockchain.ConfirmationDepth
+	}
+
+	if err := listener.Backfill(ctx, *fromBlock, *toBlock, *workers); err != nil {
+		log.LG.Fatalf("backfill failed: %v", err)
+	}
+}
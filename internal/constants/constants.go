@@ -1,3 +1,96 @@
 package constants
 
 const LifePointDecimals = 18
+
+// Membership event statuses
+const (
+	MembershipEventPending  uint8 = 0
+	MembershipEventSuccess  uint8 = 1
+	MembershipEventOrphaned uint8 = 2 // The block that included the event was replaced by a chain reorg
+	MembershipEventExpired  uint8 = 3 // The membership of the user, renewals included, has expired
+)
+
+// Membership expiry notification types
+const (
+	MembershipExpiringSoon = "membership.expiring_soon"
+	MembershipExpired      = "membership.expired"
+)
+
+// Membership durations, as emitted by the MembershipPurchased event
+const (
+	MembershipDurationOneYear    uint8 = 0
+	MembershipDurationThreeYears uint8 = 1
+)
+
+// MembershipDurationDays is the number of days a membership of each duration lasts.
+var MembershipDurationDays = map[uint8]int{
+	MembershipDurationOneYear:    365,
+	MembershipDurationThreeYears: 1095,
+}
+
+// Reward transaction statuses
+const (
+	TransferStatusFailed    int16 = -1 // The transaction could not be submitted
+	TransferStatusPending   int16 = 0  // The transaction was submitted and waits to be mined
+	TransferStatusConfirmed int16 = 1  // The transaction was mined and succeeded
+	TransferStatusReverted  int16 = 2  // The transaction was mined but reverted
+	TransferStatusDropped   int16 = 3  // The transaction was never mined and is no longer known to the node
+	TransferStatusReplaced  int16 = 4  // The transaction was replaced by another one with the same nonce
+)
+
+// TxTypeCancel is the transaction type of a zero-value self-transfer that cancels a stuck reward transaction.
+const TxTypeCancel = "CANCEL"
+
+// Transfer job statuses
+const (
+	TransferJobQueued     uint8 = 0 // The job waits for a worker, possibly for a retry
+	TransferJobProcessing uint8 = 1 // A worker is distributing the tokens of the job
+	TransferJobCompleted  uint8 = 2 // Every transfer of the job was submitted
+	TransferJobFailed     uint8 = 3 // The job ran out of attempts or has an invalid payload
+)
+
+// IdempotencyKeyHeader is the request header carrying the idempotency key of a token distribution request.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending    uint8 = 0 // The delivery waits for a worker, possibly for a retry
+	WebhookDeliveryDelivering uint8 = 1 // A worker is posting the payload to the subscriber
+	WebhookDeliveryDelivered  uint8 = 2 // The subscriber acknowledged the payload with a 2xx status
+	WebhookDeliveryDeadLetter uint8 = 3 // The delivery ran out of attempts, it is only retried when replayed
+)
+
+// Webhook event types
+const (
+	WebhookEventMembershipPurchased = "MembershipPurchased" // A membership purchase was indexed
+	WebhookEventTransferConfirmed   = "TransferConfirmed"   // A reward transaction was mined and succeeded
+	WebhookEventTransferReverted    = "TransferReverted"    // A reward transaction was mined but reverted
+)
+
+// WebhookEventTypes are the event types webhooks can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookEventMembershipPurchased,
+	WebhookEventTransferConfirmed,
+	WebhookEventTransferReverted,
+}
+
+// Headers of the webhook requests
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookIDHeader        = "X-Webhook-ID" // Event ID, the same for every delivery of the event
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature" // "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>"
+)
+
+// Token transfer statuses
+const (
+	TokenTransferSuccess  uint8 = 1
+	TokenTransferOrphaned uint8 = 2 // The block that included the transfer was replaced by a chain reorg
+)
+
+// Reconciliation statuses of reward transactions against the BulkTransfer events of the token contract
+const (
+	ReconciliationPending    int16 = 0 // No BulkTransfer event was indexed for the transaction yet
+	ReconciliationMatched    int16 = 1 // The indexed transfers match the recorded transfers
+	ReconciliationMismatched int16 = 2 // The indexed transfers differ from the recorded transfers
+)
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+type ListenerStatusDTO struct {
+	Name            string     `json:"name"`
+	ChainID         uint64     `json:"chain_id"`
+	ContractAddress string     `json:"contract_address"`
+	EventNames      []string   `json:"event_names"`
+	Running         bool       `json:"running"`
+	Paused          bool       `json:"paused"`
+	CurrentBlock    uint64     `json:"current_block"` // Next block to process
+	ChainHead       uint64     `json:"chain_head"`
+	Lag             uint64     `json:"lag"` // Blocks mined after the last processed block, including the unconfirmed ones
+	PendingRewind   *uint64    `json:"pending_rewind,omitempty"`
+	PendingReset    bool       `json:"pending_reset,omitempty"` // Whether the pending rewind also forgets the state of the listener
+	LastError       string     `json:"last_error,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+}
+
+type RewindListenerPayloadDTO struct {
+	Block uint64 `json:"block" binding:"required,min=1"` // Block to process again from
+}
@@ -3,11 +3,39 @@ package dto
 import "time"
 
 type MembershipEventDTO struct {
-	ID              uint64    `json:"id"`
-	UserAddress     string    `json:"user_address"`
-	OrderID         uint64    `json:"order_id"`
-	TransactionHash string    `json:"transaction_hash"`
-	Amount          string    `json:"amount"`
-	Status          uint8     `json:"status"`
-	EndDuration     time.Time `json:"end_duration"`
+	ID              uint64     `json:"id"`
+	UserAddress     string     `json:"user_address"`
+	OrderID         uint64     `json:"order_id"`
+	TransactionHash string     `json:"transaction_hash"`
+	Amount          string     `json:"amount"`
+	Status          uint8      `json:"status"`
+	EndDuration     time.Time  `json:"end_duration"`
+	Duration        uint8      `json:"duration"`
+	BlockNumber     uint64     `json:"block_number"`
+	BlockTimestamp  *time.Time `json:"block_timestamp"`
+	LogIndex        uint       `json:"log_index"`
+}
+
+type MembershipStatusDTO struct {
+	UserAddress string                `json:"user_address"`
+	Active      bool                  `json:"active"`
+	ExpiresAt   *time.Time            `json:"expires_at"` // Expiry with renewals stacked, nil if the user never purchased a membership
+	Purchases   []MembershipEventDTO  `json:"purchases"`
+	OnChain     *OnChainMembershipDTO `json:"on_chain,omitempty"`
+}
+
+type OnChainMembershipDTO struct {
+	Active     bool      `json:"active"`
+	IsMember   bool      `json:"is_member"`
+	Expiration time.Time `json:"expiration"`
+	Consistent bool      `json:"consistent"` // Whether the contract agrees with the indexed purchases on the membership being active
+}
+
+// MembershipExpiryNotificationDTO is the payload of a membership expiry notification.
+type MembershipExpiryNotificationDTO struct {
+	Type        string    `json:"type"` // "membership.expiring_soon" or "membership.expired"
+	UserAddress string    `json:"user_address"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	LeadTime    string    `json:"lead_time,omitempty"` // Lead time of an expiring soon notification, e.g. "168h0m0s"
+	SentAt      time.Time `json:"sent_at"`
 }
@@ -0,0 +1,9 @@
+package dto
+
+type PaginationDTOResponse struct {
+	Page     int         `json:"page"`
+	Size     int         `json:"size"`
+	Total    int64       `json:"total"`     // Number of items matching the query across all pages
+	NextPage int         `json:"next_page"` // 0 when this is the last page
+	Data     interface{} `json:"data"`
+}
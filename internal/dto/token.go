@@ -0,0 +1,46 @@
+package dto
+
+import "time"
+
+type TokenTransferDTO struct {
+	ID              uint64    `json:"id"`
+	TokenAddress    string    `json:"token_address"`
+	FromAddress     string    `json:"from_address"`
+	ToAddress       string    `json:"to_address"`
+	Amount          string    `json:"amount"` // In the smallest unit of the token
+	TransactionHash string    `json:"transaction_hash"`
+	LogIndex        uint      `json:"log_index"`
+	BlockNumber     uint64    `json:"block_number"`
+	BlockTimestamp  time.Time `json:"block_timestamp"`
+	Status          uint8     `json:"status"`
+}
+
+type TokenBalanceDTO struct {
+	TokenAddress string    `json:"token_address"`
+	Address      string    `json:"address"`
+	Balance      string    `json:"balance"`    // In the smallest unit of the token
+	LastBlock    uint64    `json:"last_block"` // Block of the last transfer applied to the balance, 0 if there was none
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TokenOnChainBalanceDTO is a token balance read from the token contract.
+type TokenOnChainBalanceDTO struct {
+	TokenAddress string    `json:"token_address"`
+	Address      string    `json:"address"`
+	Balance      string    `json:"balance"`    // In the smallest unit of the token
+	FetchedAt    time.Time `json:"fetched_at"` // When the balance was read from the chain, it may be served from cache for a few seconds
+}
+
+type TokenSupplyDTO struct {
+	TokenAddress string    `json:"token_address"`
+	TotalSupply  string    `json:"total_supply"` // In the smallest unit of the token
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+type TokenAllowanceDTO struct {
+	TokenAddress string    `json:"token_address"`
+	Owner        string    `json:"owner"`
+	Spender      string    `json:"spender"`
+	Allowance    string    `json:"allowance"` // In the smallest unit of the token
+	FetchedAt    time.Time `json:"fetched_at"`
+}
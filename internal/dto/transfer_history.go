@@ -1,12 +1,50 @@
 package dto
 
+import "time"
+
 type TransferHistoryDTO struct {
-	ID               uint64 `json:"id"`
-	RewardAddress    string `json:"reward_address"`
-	RecipientAddress string `json:"recipient_address"`
-	TransactionHash  string `json:"transaction_hash"`
-	TokenAmount      string `json:"token_amount"`
-	Status           int16  `json:"status"`
-	TxType           string `json:"tx_type"`
-	ErrorMessage     string `json:"error_message"`
+	ID                   uint64    `json:"id"`
+	RewardAddress        string    `json:"reward_address"`
+	RecipientAddress     string    `json:"recipient_address"`
+	TransactionHash      string    `json:"transaction_hash"`
+	TokenAmount          string    `json:"token_amount"`
+	Status               int16     `json:"status"`
+	TxType               string    `json:"tx_type"`
+	ErrorMessage         string    `json:"error_message"`
+	BlockNumber          uint64    `json:"block_number"`
+	GasUsed              uint64    `json:"gas_used"`
+	EffectiveGasPrice    string    `json:"effective_gas_price"`
+	Nonce                uint64    `json:"nonce"`
+	GasPrice             string    `json:"gas_price"`
+	GasTipCap            string    `json:"gas_tip_cap"`
+	ReplacesHash         string    `json:"replaces_hash"`
+	ReplacedByHash       string    `json:"replaced_by_hash"`
+	BatchID              string    `json:"batch_id"`
+	BatchIndex           int       `json:"batch_index"`
+	GasLimit             uint64    `json:"gas_limit"`
+	ReconciliationStatus int16     `json:"reconciliation_status"` // 0 not reconciled yet, 1 matched, 2 mismatched with the BulkTransfer event
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// TransferHistoryFilterDTO holds the optional filters of a transfer history query.
+type TransferHistoryFilterDTO struct {
+	RecipientAddress string
+	TxType           string
+	Status           *int16
+	TransactionHash  string
+	CreatedFrom      *time.Time // Inclusive
+	CreatedTo        *time.Time // Exclusive
+}
+
+type TransferJobDTO struct {
+	ID            string               `json:"id"`
+	Status        uint8                `json:"status"`
+	Attempts      int                  `json:"attempts"`
+	LastError     string               `json:"last_error"`
+	NextAttemptAt time.Time            `json:"next_attempt_at"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	Replayed      bool                 `json:"replayed"` // True when the job was created by an earlier request with the same idempotency key
+	Transfers     []TransferHistoryDTO `json:"transfers"`
 }
@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type CreateWebhookSubscriptionPayloadDTO struct {
+	EventType string `json:"event_type" binding:"required"`
+	URL       string `json:"url" binding:"required,url"`
+	Secret    string `json:"secret"` // Generated when empty
+}
+
+type WebhookSubscriptionDTO struct {
+	ID        uint64    `json:"id"`
+	EventType string    `json:"event_type"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // Only returned when the subscription is created
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryDTO struct {
+	ID             uint64          `json:"id"`
+	SubscriptionID uint64          `json:"subscription_id"`
+	EventType      string          `json:"event_type"`
+	EventID        string          `json:"event_id"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         uint8           `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error"`
+	ResponseStatus int             `json:"response_status"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	Removed        bool            `json:"removed"`
+	Superseded     bool            `json:"superseded"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// WebhookDeliveryFilterDTO holds the optional filters of a webhook delivery query.
+type WebhookDeliveryFilterDTO struct {
+	SubscriptionID uint64
+	EventType      string
+	Status         *uint8
+}
+
+// WebhookEventDTO is the body posted to the subscribers of an event. An event removed from the chain by a reorg
+// is posted again with removed set, and posted once more without it if another block includes it.
+type WebhookEventDTO struct {
+	ID        string      `json:"id"` // The same for every delivery of the event
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Removed   bool        `json:"removed,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// TransferTransactionEventDTO is the data of the events published when a reward transaction is mined.
+type TransferTransactionEventDTO struct {
+	TransactionHash string               `json:"transaction_hash"`
+	BlockNumber     uint64               `json:"block_number"`
+	Status          int16                `json:"status"`
+	Transfers       []TransferHistoryDTO `json:"transfers"`
+}
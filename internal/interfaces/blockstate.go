@@ -1,8 +1,22 @@
 package interfaces
 
-import "context"
+import (
+	"context"
+
+	"github.com/genefriendway/onchain-handler/internal/model"
+)
 
 type BlockStateRepository interface {
-	GetLastProcessedBlock(ctx context.Context) (uint64, error)
-	UpdateLastProcessedBlock(ctx context.Context, blockNumber uint64) error
+	GetLastProcessedBlock(ctx context.Context, key model.ListenerKey) (uint64, error)
+	UpdateLastProcessedBlock(ctx context.Context, key model.ListenerKey, blockNumber uint64) error
+	ClaimLegacyBlockState(ctx context.Context, key model.ListenerKey) error
+	ResetListenerState(ctx context.Context, key model.ListenerKey) error
+	SaveProcessedBlocks(ctx context.Context, key model.ListenerKey, blocks []model.ProcessedBlock) error
+	GetProcessedBlocks(ctx context.Context, key model.ListenerKey) ([]model.ProcessedBlock, error)
+	DeleteProcessedBlocksFrom(ctx context.Context, key model.ListenerKey, blockNumber uint64) error
+	DeleteProcessedBlocksBefore(ctx context.Context, key model.ListenerKey, blockNumber uint64) error
+	SavePublishedEvents(ctx context.Context, key model.ListenerKey, events []model.PublishedEvent) error
+	GetPublishedEventsFrom(ctx context.Context, key model.ListenerKey, blockNumber uint64) ([]model.PublishedEvent, error)
+	DeletePublishedEventsFrom(ctx context.Context, key model.ListenerKey, blockNumber uint64) error
+	DeletePublishedEventsBefore(ctx context.Context, key model.ListenerKey, blockNumber uint64) error
 }
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/genefriendway/onchain-handler/internal/model"
+)
+
+type ContractEventRepository interface {
+	CreateContractEvent(ctx context.Context, event model.ContractEvent) error
+	RemoveContractEvent(ctx context.Context, event model.ContractEvent) error
+}
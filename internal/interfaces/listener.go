@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+)
+
+type ListenerUCase interface {
+	GetListeners(ctx context.Context) ([]dto.ListenerStatusDTO, error)
+	PauseListener(ctx context.Context, name string) (*dto.ListenerStatusDTO, error)
+	ResumeListener(ctx context.Context, name string) (*dto.ListenerStatusDTO, error)
+	RewindListener(ctx context.Context, name string, block uint64) (*dto.ListenerStatusDTO, error)
+	ResetListener(ctx context.Context, name string) (*dto.ListenerStatusDTO, error)
+}
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/genefriendway/onchain-handler/internal/dto"
 	"github.com/genefriendway/onchain-handler/internal/model"
@@ -11,9 +12,17 @@ type MembershipRepository interface {
 	CreateMembershipEventHistory(ctx context.Context, model model.MembershipEvent) error
 	GetMembershipEventByOrderID(ctx context.Context, orderID uint64) (*model.MembershipEvent, error)
 	GetMembershipEventsByOrderIDs(ctx context.Context, orderIDs []uint64) ([]model.MembershipEvent, error)
+	MarkMembershipEventsOrphanedFromBlock(ctx context.Context, blockNumber uint64) (int64, error)
+	GetMembershipEventsByUserAddress(ctx context.Context, userAddress string) ([]model.MembershipEvent, error)
+	GetUserAddressesWithMembershipsEndingBefore(ctx context.Context, before time.Time, afterAddress string, limit int) ([]string, error)
+	ExpireMembershipEvents(ctx context.Context, userAddress string) (int64, error)
+	HasMembershipNotification(ctx context.Context, notification model.MembershipNotification) (bool, error)
+	CreateMembershipNotification(ctx context.Context, notification model.MembershipNotification) error
 }
 
 type MembershipUCase interface {
 	GetMembershipEventByOrderID(ctx context.Context, orderID uint64) (*dto.MembershipEventDTO, error)
 	GetMembershipEventsByOrderIDs(ctx context.Context, orderIDs []uint64) ([]dto.MembershipEventDTO, error)
+	GetMembershipStatus(ctx context.Context, userAddress string, verifyOnChain bool) (*dto.MembershipStatusDTO, error)
+	ProcessMembershipExpiries(ctx context.Context) error
 }
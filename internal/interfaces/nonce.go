@@ -0,0 +1,8 @@
+package interfaces
+
+import "context"
+
+type NonceRepository interface {
+	GetNextNonce(ctx context.Context, chainID uint64, address string) (uint64, error)
+	SaveNextNonce(ctx context.Context, chainID uint64, address string, nextNonce uint64) error
+}
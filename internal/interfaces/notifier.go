@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+)
+
+// MembershipNotifier delivers membership expiry notifications.
+type MembershipNotifier interface {
+	NotifyMembershipExpiry(ctx context.Context, notification dto.MembershipExpiryNotificationDTO) error
+}
@@ -0,0 +1,29 @@
+package interfaces
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/model"
+)
+
+type TokenRepository interface {
+	CreateTokenTransfer(ctx context.Context, transfer model.TokenTransfer) error
+	GetTokenTransfersByTxHash(ctx context.Context, txHash string) ([]model.TokenTransfer, error)
+	GetTokenTransfersFromBlock(ctx context.Context, tokenAddress string, blockNumber uint64) ([]model.TokenTransfer, error)
+	MarkTokenTransfersOrphanedFromBlock(ctx context.Context, tokenAddress string, blockNumber uint64) (int64, error)
+	GetTokenTransfers(ctx context.Context, tokenAddress, address string, limit, offset int) ([]model.TokenTransfer, int64, error)
+	AddTokenBalance(ctx context.Context, tokenAddress, address string, delta *big.Int, blockNumber uint64) error
+	GetTokenBalance(ctx context.Context, tokenAddress, address string) (*model.TokenBalance, error)
+}
+
+type TokenUCase interface {
+	GetLedgerBalance(ctx context.Context, address string) (*dto.TokenBalanceDTO, error)
+	GetTokenTransfers(ctx context.Context, address string, page, size int) (dto.PaginationDTOResponse, error)
+	GetOnChainBalance(ctx context.Context, address string) (*dto.TokenOnChainBalanceDTO, error)
+	GetRewardBalance(ctx context.Context) (*dto.TokenOnChainBalanceDTO, error)
+	GetMembershipContractBalance(ctx context.Context) (*dto.TokenOnChainBalanceDTO, error)
+	GetTotalSupply(ctx context.Context) (*dto.TokenSupplyDTO, error)
+	GetAllowance(ctx context.Context, owner, spender string) (*dto.TokenAllowanceDTO, error)
+}
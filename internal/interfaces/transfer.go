@@ -2,15 +2,40 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
 	"github.com/genefriendway/onchain-handler/internal/dto"
 	"github.com/genefriendway/onchain-handler/internal/model"
 )
 
 type TransferRepository interface {
-	CreateTransferHistories(ctx context.Context, models []model.TransferHistory) error
+	SaveTransferHistories(ctx context.Context, models []model.TransferHistory) error
+	SaveSignedTransferHistories(ctx context.Context, signedTx *model.SignedTransaction, models []model.TransferHistory) error
+	GetSignedTransaction(ctx context.Context, txHash string) (*model.SignedTransaction, error)
+	GetTransferHistoriesByStatus(ctx context.Context, status int16) ([]model.TransferHistory, error)
+	GetTransferHistoriesByTxHash(ctx context.Context, txHash string) ([]model.TransferHistory, error)
+	UpdateTransferHistoriesByTxHash(ctx context.Context, txHash string, update model.TransferHistory) error
+	ReplaceTransferHistories(ctx context.Context, oldTxHash string, replacement *model.SignedTransaction, replacements []model.TransferHistory) error
+	RevertTransferReplacement(ctx context.Context, oldTxHash, newTxHash string) error
+	GetTransferHistories(ctx context.Context, filter dto.TransferHistoryFilterDTO, limit, offset int) ([]model.TransferHistory, int64, error)
+	GetTransferHistoryByID(ctx context.Context, id uint64) (*model.TransferHistory, error)
+	GetTransferHistoriesByBatchID(ctx context.Context, batchID string) ([]model.TransferHistory, error)
+	HasPendingTransfersInNonceRange(ctx context.Context, rewardAddress string, fromNonce, toNonce uint64) (bool, error)
+	CreateTransferJob(ctx context.Context, job *model.TransferJob) error
+	GetTransferJobByID(ctx context.Context, id string) (*model.TransferJob, error)
+	GetTransferJobByIdempotencyKey(ctx context.Context, idempotencyKey string) (*model.TransferJob, error)
+	ClaimTransferJob(ctx context.Context, staleBefore time.Time) (*model.TransferJob, error)
+	RefreshTransferJobLock(ctx context.Context, id string, attempt int) (bool, error)
+	UpdateTransferJob(ctx context.Context, job *model.TransferJob) error
+	UpdateReconciliationStatusByTxHashes(ctx context.Context, txHashes []string, status int16) error
 }
 
 type TransferUCase interface {
-	DistributeTokens(ctx context.Context, payloads []dto.TransferTokenPayloadDTO) error
+	EnqueueTransfer(ctx context.Context, idempotencyKey string, payloads []dto.TransferTokenPayloadDTO) (*dto.TransferJobDTO, error)
+	GetTransferJob(ctx context.Context, id string) (*dto.TransferJobDTO, error)
+	ProcessNextTransferJob(ctx context.Context) (bool, error)
+	GetTransferHistoriesByTxHash(ctx context.Context, txHash string) ([]dto.TransferHistoryDTO, error)
+	GetTransferHistories(ctx context.Context, filter dto.TransferHistoryFilterDTO, page, size int) (dto.PaginationDTOResponse, error)
+	GetTransferHistoryByID(ctx context.Context, id uint64) (*dto.TransferHistoryDTO, error)
+	CancelTransaction(ctx context.Context, txHash string) (string, error)
 }
@@ -0,0 +1,10 @@
+package interfaces
+
+import "context"
+
+// UnitOfWork groups repository calls into a single database transaction.
+type UnitOfWork interface {
+	// Do runs fn inside a transaction that is committed when fn returns nil and rolled back otherwise.
+	// Repository calls made with the context passed to fn take part in the transaction.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/model"
+)
+
+type WebhookRepository interface {
+	CreateWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error)
+	GetWebhookSubscriptionByID(ctx context.Context, id uint64) (*model.WebhookSubscription, error)
+	GetActiveWebhookSubscriptionsByEventType(ctx context.Context, eventType string) ([]model.WebhookSubscription, error)
+	DeactivateWebhookSubscription(ctx context.Context, id uint64) (bool, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error
+	SupersedeWebhookDeliveries(ctx context.Context, eventID string) ([]uint64, error)
+	ClaimWebhookDelivery(ctx context.Context, staleBefore time.Time) (*model.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	GetWebhookDeliveryByID(ctx context.Context, id uint64) (*model.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, filter dto.WebhookDeliveryFilterDTO, limit, offset int) ([]model.WebhookDelivery, int64, error)
+	ReplayWebhookDelivery(ctx context.Context, id uint64) (bool, error)
+}
+
+// WebhookPublisher queues an event for delivery to the webhooks subscribed to its type, and retracts the events
+// removed from the chain by a reorg. Publishing with the context of a unit of work queues the deliveries in its transaction.
+type WebhookPublisher interface {
+	Publish(ctx context.Context, eventType, eventID string, data interface{}) error
+	Retract(ctx context.Context, eventType, eventID string, data interface{}) error
+}
+
+type WebhookUCase interface {
+	WebhookPublisher
+	CreateSubscription(ctx context.Context, payload dto.CreateWebhookSubscriptionPayloadDTO) (*dto.WebhookSubscriptionDTO, error)
+	GetSubscriptions(ctx context.Context) ([]dto.WebhookSubscriptionDTO, error)
+	DeleteSubscription(ctx context.Context, id uint64) (bool, error)
+	GetDeliveries(ctx context.Context, filter dto.WebhookDeliveryFilterDTO, page, size int) (dto.PaginationDTOResponse, error)
+	ReplayDelivery(ctx context.Context, id uint64) (*dto.WebhookDeliveryDTO, error)
+	ProcessNextDelivery(ctx context.Context) (bool, error)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminAuth rejects requests that do not carry the given admin key in the X-Admin-Key header.
+// All requests are rejected when no admin key is configured.
+func AdminAuth(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled"})
+			return
+		}
+
+		providedKey := c.GetHeader(AdminKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(adminKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin key"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,11 +1,42 @@
 package model
 
-// BlockState represents the state of the last processed block.
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BlockState represents the state of the last processed block of a single listener.
 type BlockState struct {
-	ID        uint   `json:"id" gorm:"primaryKey;autoIncrement"`
-	LastBlock uint64 `json:"last_block"` // The last processed block
+	ID              uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ListenerKey     string    `json:"listener_key"`     // Unique identity of the listener, see ListenerKey
+	ChainID         uint64    `json:"chain_id"`         // Chain the listener is indexing
+	ContractAddress string    `json:"contract_address"` // Contract the listener is indexing
+	EventSet        string    `json:"event_set"`        // Comma-separated, sorted list of indexed events
+	LastBlock       uint64    `json:"last_block"`       // The last processed block
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 func (m *BlockState) TableName() string {
 	return "block_state"
 }
+
+// ListenerKey identifies an event listener by the chain, contract and set of events it indexes.
+type ListenerKey struct {
+	ChainID         uint64
+	ContractAddress string
+	EventNames      []string
+}
+
+// EventSet returns the indexed event names as a sorted, comma-separated list.
+func (k ListenerKey) EventSet() string {
+	eventNames := append([]string(nil), k.EventNames...)
+	sort.Strings(eventNames)
+	return strings.Join(eventNames, ",")
+}
+
+// String returns the canonical form of the key, e.g. "97:0xabc...:MembershipPurchased".
+func (k ListenerKey) String() string {
+	return fmt.Sprintf("%d:%s:%s", k.ChainID, strings.ToLower(k.ContractAddress), k.EventSet())
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ContractEvent is an event published by a listener to the postgres event sink.
+type ContractEvent struct {
+	ID         uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	EventName  string    `json:"event_name"`
+	EventID    string    `json:"event_id"`
+	Payload    string    `json:"payload" gorm:"type:jsonb"`
+	Removed    bool      `json:"removed"`    // Whether the row retracts the event, removed from the chain by a reorg
+	Superseded bool      `json:"superseded"` // Whether a later row retracted or published the event again
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (m *ContractEvent) TableName() string {
+	return "contract_event"
+}
@@ -7,15 +7,20 @@ import (
 )
 
 type MembershipEvent struct {
-	ID              uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserAddress     string    `json:"user_address"`
-	OrderID         uint64    `json:"order_id"`
-	TransactionHash string    `json:"transaction_hash"`
-	Amount          string    `json:"amount"`
-	Status          uint8     `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	EndDuration     time.Time `json:"end_duration"`
+	ID              uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserAddress     string     `json:"user_address"`
+	OrderID         uint64     `json:"order_id"`
+	TransactionHash string     `json:"transaction_hash"`
+	Amount          string     `json:"amount"`
+	Status          uint8      `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	EndDuration     time.Time  `json:"end_duration"`
+	Duration        uint8      `json:"duration"` // 0 for 1 year, 1 for 3 years
+	BlockNumber     uint64     `json:"block_number"`
+	BlockHash       string     `json:"block_hash"`
+	BlockTimestamp  *time.Time `json:"block_timestamp"` // Nil for events indexed before block timestamps were stored
+	LogIndex        uint       `json:"log_index"`
 }
 
 func (m *MembershipEvent) TableName() string {
@@ -31,5 +36,17 @@ func (m *MembershipEvent) ToDto() dto.MembershipEventDTO {
 		Amount:          m.Amount,
 		Status:          m.Status,
 		EndDuration:     m.EndDuration,
+		Duration:        m.Duration,
+		BlockNumber:     m.BlockNumber,
+		BlockTimestamp:  m.BlockTimestamp,
+		LogIndex:        m.LogIndex,
 	}
 }
+
+// PurchasedAt returns the time the membership was purchased, which is the block timestamp when it is known.
+func (m *MembershipEvent) PurchasedAt() time.Time {
+	if m.BlockTimestamp != nil {
+		return *m.BlockTimestamp
+	}
+	return m.CreatedAt
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// MembershipNotification records a delivered membership expiry notification.
+type MembershipNotification struct {
+	ID               uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserAddress      string    `json:"user_address"`
+	NotificationType string    `json:"notification_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	LeadTime         int64     `json:"lead_time"` // In seconds, 0 for expired notifications
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (m *MembershipNotification) TableName() string {
+	return "membership_notification"
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ProcessedBlock stores the hash of a block recently processed by a listener, used to detect chain reorganizations.
+type ProcessedBlock struct {
+	ListenerKey string    `json:"listener_key" gorm:"primaryKey"`
+	BlockNumber uint64    `json:"block_number" gorm:"primaryKey;autoIncrement:false"`
+	BlockHash   string    `json:"block_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (m *ProcessedBlock) TableName() string {
+	return "processed_block"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// PublishedEvent records an event a listener published from a recent block, so that the event can be retracted
+// from the sinks when a chain reorg replaces the block.
+type PublishedEvent struct {
+	ListenerKey string    `json:"listener_key" gorm:"primaryKey"`
+	EventName   string    `json:"event_name" gorm:"primaryKey"`
+	EventID     string    `json:"event_id" gorm:"primaryKey"`
+	BlockNumber uint64    `json:"block_number"`
+	Payload     string    `json:"payload" gorm:"type:jsonb"` // Payload the event was published with
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (m *PublishedEvent) TableName() string {
+	return "published_event"
+}
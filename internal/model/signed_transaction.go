@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// SignedTransaction stores the binary encoding of a signed reward transaction, so that it can be rebroadcast.
+type SignedTransaction struct {
+	TransactionHash string    `json:"transaction_hash" gorm:"primaryKey"`
+	RawTransaction  []byte    `json:"raw_transaction"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (m *SignedTransaction) TableName() string {
+	return "signed_transaction"
+}
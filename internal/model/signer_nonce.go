@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// SignerNonce stores the next nonce to use for transactions signed by an account.
+type SignerNonce struct {
+	ChainID   uint64    `json:"chain_id" gorm:"primaryKey;autoIncrement:false"`
+	Address   string    `json:"address" gorm:"primaryKey"`
+	NextNonce uint64    `json:"next_nonce"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *SignerNonce) TableName() string {
+	return "signer_nonce"
+}
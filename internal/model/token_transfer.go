@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+)
+
+// TokenTransfer is a transfer of the LifePoint token, indexed from a Transfer event.
+type TokenTransfer struct {
+	ID              uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	TokenAddress    string    `json:"token_address"`
+	FromAddress     string    `json:"from_address"`
+	ToAddress       string    `json:"to_address"`
+	Amount          string    `json:"amount"` // In the smallest unit of the token
+	TransactionHash string    `json:"transaction_hash"`
+	LogIndex        uint      `json:"log_index"`
+	BlockNumber     uint64    `json:"block_number"`
+	BlockHash       string    `json:"block_hash"`
+	BlockTimestamp  time.Time `json:"block_timestamp"`
+	Status          uint8     `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (m *TokenTransfer) TableName() string {
+	return "token_transfer"
+}
+
+func (m *TokenTransfer) ToDto() dto.TokenTransferDTO {
+	return dto.TokenTransferDTO{
+		ID:              m.ID,
+		TokenAddress:    m.TokenAddress,
+		FromAddress:     m.FromAddress,
+		ToAddress:       m.ToAddress,
+		Amount:          m.Amount,
+		TransactionHash: m.TransactionHash,
+		LogIndex:        m.LogIndex,
+		BlockNumber:     m.BlockNumber,
+		BlockTimestamp:  m.BlockTimestamp,
+		Status:          m.Status,
+	}
+}
+
+// TokenBalance is the balance of an address computed from the indexed token transfers.
+type TokenBalance struct {
+	TokenAddress string    `json:"token_address" gorm:"primaryKey"`
+	Address      string    `json:"address" gorm:"primaryKey"`
+	Balance      string    `json:"balance"`    // In the smallest unit of the token
+	LastBlock    uint64    `json:"last_block"` // Block of the last transfer applied to the balance
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (m *TokenBalance) TableName() string {
+	return "token_balance"
+}
+
+func (m *TokenBalance) ToDto() dto.TokenBalanceDTO {
+	return dto.TokenBalanceDTO{
+		TokenAddress: m.TokenAddress,
+		Address:      m.Address,
+		Balance:      m.Balance,
+		LastBlock:    m.LastBlock,
+		UpdatedAt:    m.UpdatedAt,
+	}
+}
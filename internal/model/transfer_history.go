@@ -7,16 +7,28 @@ import (
 )
 
 type TransferHistory struct {
-	ID               uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
-	RewardAddress    string    `json:"reward_address"`
-	RecipientAddress string    `json:"recipient_address"`
-	TransactionHash  string    `json:"transaction_hash"`
-	TokenAmount      string    `json:"token_amount"`
-	Status           int16     `json:"status"`
-	ErrorMessage     string    `json:"error_message"`
-	TxType           string    `json:"tx_type"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                   uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	RewardAddress        string    `json:"reward_address"`
+	RecipientAddress     string    `json:"recipient_address"`
+	TransactionHash      string    `json:"transaction_hash"`
+	TokenAmount          string    `json:"token_amount"`
+	Status               int16     `json:"status"`
+	ErrorMessage         string    `json:"error_message"`
+	TxType               string    `json:"tx_type"`
+	BlockNumber          uint64    `json:"block_number"`
+	GasUsed              uint64    `json:"gas_used"`
+	EffectiveGasPrice    string    `json:"effective_gas_price" gorm:"default:null"`
+	Nonce                uint64    `json:"nonce"`
+	GasPrice             string    `json:"gas_price" gorm:"default:null"`   // Gas price, or max fee per gas for EIP-1559 transactions
+	GasTipCap            string    `json:"gas_tip_cap" gorm:"default:null"` // Max priority fee per gas for EIP-1559 transactions
+	ReplacesHash         string    `json:"replaces_hash"`
+	ReplacedByHash       string    `json:"replaced_by_hash"`
+	BatchID              string    `json:"batch_id" gorm:"default:null"` // Distribution request the transfer was sent in
+	BatchIndex           int       `json:"batch_index"`                  // Bulk transfer transaction of the distribution request
+	GasLimit             uint64    `json:"gas_limit"`
+	ReconciliationStatus int16     `json:"reconciliation_status"` // Comparison with the BulkTransfer event of the transaction
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
 }
 
 func (m *TransferHistory) TableName() string {
@@ -25,13 +37,27 @@ func (m *TransferHistory) TableName() string {
 
 func (m *TransferHistory) ToDto() dto.TransferHistoryDTO {
 	return dto.TransferHistoryDTO{
-		ID:               m.ID,
-		RewardAddress:    m.RewardAddress,
-		RecipientAddress: m.RecipientAddress,
-		TransactionHash:  m.TransactionHash,
-		TokenAmount:      m.TokenAmount,
-		Status:           m.Status,
-		TxType:           m.TxType,
-		ErrorMessage:     m.ErrorMessage,
+		ID:                   m.ID,
+		RewardAddress:        m.RewardAddress,
+		RecipientAddress:     m.RecipientAddress,
+		TransactionHash:      m.TransactionHash,
+		TokenAmount:          m.TokenAmount,
+		Status:               m.Status,
+		TxType:               m.TxType,
+		ErrorMessage:         m.ErrorMessage,
+		BlockNumber:          m.BlockNumber,
+		GasUsed:              m.GasUsed,
+		EffectiveGasPrice:    m.EffectiveGasPrice,
+		Nonce:                m.Nonce,
+		GasPrice:             m.GasPrice,
+		GasTipCap:            m.GasTipCap,
+		ReplacesHash:         m.ReplacesHash,
+		ReplacedByHash:       m.ReplacedByHash,
+		BatchID:              m.BatchID,
+		BatchIndex:           m.BatchIndex,
+		GasLimit:             m.GasLimit,
+		ReconciliationStatus: m.ReconciliationStatus,
+		CreatedAt:            m.CreatedAt,
+		UpdatedAt:            m.UpdatedAt,
 	}
 }
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+)
+
+// TransferJob is a token distribution request queued for the transfer workers.
+// Its ID is also the batch ID of the transfer histories created for it.
+type TransferJob struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	IdempotencyKey string     `json:"idempotency_key" gorm:"default:null"`
+	RequestHash    string     `json:"request_hash"` // SHA-256 of the payload, to detect an idempotency key reused for another request
+	Payload        string     `json:"payload" gorm:"type:jsonb"`
+	Status         uint8      `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LockedAt       *time.Time `json:"locked_at"` // Time a worker started processing the job
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (m *TransferJob) TableName() string {
+	return "transfer_job"
+}
+
+func (m *TransferJob) ToDto() dto.TransferJobDTO {
+	return dto.TransferJobDTO{
+		ID:            m.ID,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		NextAttemptAt: m.NextAttemptAt,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
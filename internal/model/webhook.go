@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+)
+
+// WebhookSubscription is a subscriber URL receiving the events of a type.
+type WebhookSubscription struct {
+	ID        uint64    `json:"id" gorm:"primaryKey;autoIncrement"`
+	EventType string    `json:"event_type"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"` // Key of the HMAC-SHA256 signature of the payloads
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *WebhookSubscription) TableName() string {
+	return "webhook_subscription"
+}
+
+func (m *WebhookSubscription) ToDto() dto.WebhookSubscriptionDTO {
+	return dto.WebhookSubscriptionDTO{
+		ID:        m.ID,
+		EventType: m.EventType,
+		URL:       m.URL,
+		Active:    m.Active,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+// WebhookDelivery is the delivery of an event to a subscriber.
+type WebhookDelivery struct {
+	ID             uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	SubscriptionID uint64     `json:"subscription_id"`
+	EventType      string     `json:"event_type"`
+	EventID        string     `json:"event_id"`
+	Payload        string     `json:"payload" gorm:"type:jsonb"` // Body posted to the subscriber
+	Status         uint8      `json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error"`
+	ResponseStatus int        `json:"response_status"` // HTTP status of the last attempt, 0 if there was no response
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LockedAt       *time.Time `json:"locked_at"` // Time a worker started the current attempt
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	Removed        bool       `json:"removed"`    // Whether the delivery retracts the event, removed from the chain by a reorg
+	Superseded     bool       `json:"superseded"` // Whether the event was retracted since, in which case the delivery is no longer attempted
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (m *WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
+
+func (m *WebhookDelivery) ToDto() dto.WebhookDeliveryDTO {
+	return dto.WebhookDeliveryDTO{
+		ID:             m.ID,
+		SubscriptionID: m.SubscriptionID,
+		EventType:      m.EventType,
+		EventID:        m.EventID,
+		Payload:        []byte(m.Payload),
+		Status:         m.Status,
+		Attempts:       m.Attempts,
+		LastError:      m.LastError,
+		ResponseStatus: m.ResponseStatus,
+		NextAttemptAt:  m.NextAttemptAt,
+		DeliveredAt:    m.DeliveredAt,
+		Removed:        m.Removed,
+		Superseded:     m.Superseded,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
 	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
 )
 
 type blockstateRepository struct {
@@ -21,12 +23,10 @@ func NewBlockstateRepository(db *gorm.DB) interfaces.BlockStateRepository {
 	}
 }
 
-// GetLastProcessedBlock retrieves the last processed block from the database.
-func (r *blockstateRepository) GetLastProcessedBlock(ctx context.Context) (uint64, error) {
-	var blockState model.BlockState
-
-	// Attempt to find the first block state entry in the database.
-	if err := r.db.WithContext(ctx).First(&blockState).Error; err != nil {
+// GetLastProcessedBlock retrieves the last processed block of the given listener from the database.
+func (r *blockstateRepository) GetLastProcessedBlock(ctx context.Context, key model.ListenerKey) (uint64, error) {
+	blockState, err := r.findBlockState(ctx, key)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// Return 0 if no record is found (first run scenario)
 			return 0, nil
@@ -37,21 +37,151 @@ func (r *blockstateRepository) GetLastProcessedBlock(ctx context.Context) (uint6
 	return blockState.LastBlock, nil
 }
 
-// UpdateLastProcessedBlock updates the last processed block in the database.
-func (r *blockstateRepository) UpdateLastProcessedBlock(ctx context.Context, blockNumber uint64) error {
-	var blockState model.BlockState
-
-	// Check if the block state record exists
-	if err := r.db.WithContext(ctx).First(&blockState).Error; err != nil {
+// UpdateLastProcessedBlock updates the last processed block of the given listener in the database.
+func (r *blockstateRepository) UpdateLastProcessedBlock(ctx context.Context, key model.ListenerKey, blockNumber uint64) error {
+	blockState, err := r.findBlockState(ctx, key)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// If no record is found, create a new one
-			blockState.LastBlock = blockNumber
-			return r.db.WithContext(ctx).Create(&blockState).Error
+			blockState = &model.BlockState{}
+		} else {
+			return err
 		}
-		return err
 	}
 
-	// If record exists, update the last block number
+	blockState.ListenerKey = key.String()
+	blockState.ChainID = key.ChainID
+	blockState.ContractAddress = key.ContractAddress
+	blockState.EventSet = key.EventSet()
 	blockState.LastBlock = blockNumber
-	return r.db.WithContext(ctx).Save(&blockState).Error
+	return unitofwork.DB(ctx, r.db).Save(blockState).Error
+}
+
+// ClaimLegacyBlockState assigns the cursor row that predates per-listener cursors to the given listener,
+// unless the listener already has a cursor. It does nothing once the legacy row has been claimed.
+func (r *blockstateRepository) ClaimLegacyBlockState(ctx context.Context, key model.ListenerKey) error {
+	return unitofwork.DB(ctx, r.db).
+		Model(&model.BlockState{}).
+		Where("id = (SELECT MIN(id) FROM block_state WHERE listener_key IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM block_state WHERE listener_key = ?)", key.String()).
+		Updates(map[string]interface{}{
+			"listener_key":     key.String(),
+			"chain_id":         key.ChainID,
+			"contract_address": key.ContractAddress,
+			"event_set":        key.EventSet(),
+		}).Error
+}
+
+// ResetListenerState removes the cursor, block hashes and published events of the given listener,
+// so that it starts over from its start block.
+func (r *blockstateRepository) ResetListenerState(ctx context.Context, key model.ListenerKey) error {
+	return unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("listener_key = ?", key.String()).Delete(&model.BlockState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("listener_key = ?", key.String()).Delete(&model.ProcessedBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("listener_key = ?", key.String()).Delete(&model.PublishedEvent{}).Error
+	})
+}
+
+// findBlockState looks up the cursor row of the given listener.
+func (r *blockstateRepository) findBlockState(ctx context.Context, key model.ListenerKey) (*model.BlockState, error) {
+	var blockState model.BlockState
+	if err := unitofwork.DB(ctx, r.db).Where("listener_key = ?", key.String()).First(&blockState).Error; err != nil {
+		return nil, err
+	}
+	return &blockState, nil
+}
+
+// SaveProcessedBlocks stores the hashes of blocks processed by the given listener,
+// overwriting any hash already stored for the same block number.
+func (r *blockstateRepository) SaveProcessedBlocks(ctx context.Context, key model.ListenerKey, blocks []model.ProcessedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	for index := range blocks {
+		blocks[index].ListenerKey = key.String()
+	}
+
+	return unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "listener_key"}, {Name: "block_number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"block_hash", "created_at"}),
+		}).
+		Create(&blocks).Error
+}
+
+// GetProcessedBlocks retrieves the stored processed blocks of the given listener, newest first.
+func (r *blockstateRepository) GetProcessedBlocks(ctx context.Context, key model.ListenerKey) ([]model.ProcessedBlock, error) {
+	var blocks []model.ProcessedBlock
+	if err := unitofwork.DB(ctx, r.db).
+		Where("listener_key = ?", key.String()).
+		Order("block_number DESC").
+		Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// DeleteProcessedBlocksFrom removes the stored hashes of all blocks at or above the given block number.
+func (r *blockstateRepository) DeleteProcessedBlocksFrom(ctx context.Context, key model.ListenerKey, blockNumber uint64) error {
+	return unitofwork.DB(ctx, r.db).
+		Where("listener_key = ? AND block_number >= ?", key.String(), blockNumber).
+		Delete(&model.ProcessedBlock{}).Error
+}
+
+// DeleteProcessedBlocksBefore removes the stored hashes of all blocks below the given block number.
+func (r *blockstateRepository) DeleteProcessedBlocksBefore(ctx context.Context, key model.ListenerKey, blockNumber uint64) error {
+	return unitofwork.DB(ctx, r.db).
+		Where("listener_key = ? AND block_number < ?", key.String(), blockNumber).
+		Delete(&model.ProcessedBlock{}).Error
+}
+
+// SavePublishedEvents stores the events published by the given listener, overwriting any event already stored
+// with the same name and ID.
+func (r *blockstateRepository) SavePublishedEvents(ctx context.Context, key model.ListenerKey, events []model.PublishedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	for index := range events {
+		events[index].ListenerKey = key.String()
+	}
+
+	return unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "listener_key"}, {Name: "event_name"}, {Name: "event_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"block_number", "payload", "created_at"}),
+		}).
+		Create(&events).Error
+}
+
+// GetPublishedEventsFrom retrieves the events published by the given listener from the blocks at or above the given
+// block number, oldest block first.
+func (r *blockstateRepository) GetPublishedEventsFrom(ctx context.Context, key model.ListenerKey, blockNumber uint64) ([]model.PublishedEvent, error) {
+	var events []model.PublishedEvent
+	if err := unitofwork.DB(ctx, r.db).
+		Where("listener_key = ? AND block_number >= ?", key.String(), blockNumber).
+		Order("block_number ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DeletePublishedEventsFrom removes the events published from the blocks at or above the given block number.
+func (r *blockstateRepository) DeletePublishedEventsFrom(ctx context.Context, key model.ListenerKey, blockNumber uint64) error {
+	return unitofwork.DB(ctx, r.db).
+		Where("listener_key = ? AND block_number >= ?", key.String(), blockNumber).
+		Delete(&model.PublishedEvent{}).Error
+}
+
+// DeletePublishedEventsBefore removes the events published from the blocks below the given block number.
+func (r *blockstateRepository) DeletePublishedEventsBefore(ctx context.Context, key model.ListenerKey, blockNumber uint64) error {
+	return unitofwork.DB(ctx, r.db).
+		Where("listener_key = ? AND block_number < ?", key.String(), blockNumber).
+		Delete(&model.PublishedEvent{}).Error
 }
@@ -0,0 +1,76 @@
+package contractevent
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
+)
+
+// ContractEventNotifyChannel is the channel notified with the id of each new contract event.
+const ContractEventNotifyChannel = "contract_event"
+
+type contractEventRepository struct {
+	db *gorm.DB
+}
+
+func NewContractEventRepository(db *gorm.DB) interfaces.ContractEventRepository {
+	return &contractEventRepository{
+		db: db,
+	}
+}
+
+// CreateContractEvent stores an event and notifies the listeners of ContractEventNotifyChannel, which only
+// receive the notification once the surrounding transaction is committed. An event stored again is ignored.
+func (r *contractEventRepository) CreateContractEvent(ctx context.Context, event model.ContractEvent) error {
+	db := unitofwork.DB(ctx, r.db)
+	result := db.
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "event_name"}, {Name: "event_id"}, {Name: "removed"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "NOT superseded"}}},
+			DoNothing:   true,
+		}).
+		Create(&event)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create contract event: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return r.notify(db, event.ID)
+}
+
+// RemoveContractEvent retracts an event removed from the chain by a reorg: the rows stored for the event are
+// superseded, and a row with removed set is stored and notified. Events that were never stored are ignored.
+func (r *contractEventRepository) RemoveContractEvent(ctx context.Context, event model.ContractEvent) error {
+	db := unitofwork.DB(ctx, r.db)
+	result := db.
+		Model(&model.ContractEvent{}).
+		Where("event_name = ? AND event_id = ? AND NOT superseded", event.EventName, event.EventID).
+		Update("superseded", true)
+	if result.Error != nil {
+		return fmt.Errorf("failed to supersede contract event: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	event.Removed = true
+	if err := db.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to create removed contract event: %w", err)
+	}
+	return r.notify(db, event.ID)
+}
+
+func (r *contractEventRepository) notify(db *gorm.DB, id uint64) error {
+	if err := db.Exec("SELECT pg_notify(?, ?)", ContractEventNotifyChannel, fmt.Sprint(id)).Error; err != nil {
+		return fmt.Errorf("failed to notify contract event: %w", err)
+	}
+	return nil
+}
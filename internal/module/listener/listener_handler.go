@@ -0,0 +1,179 @@
+package listener
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genefriendway/onchain-handler/blockchain"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+type ListenerHandler struct {
+	UCase interfaces.ListenerUCase
+}
+
+// NewListenerHandler initializes the ListenerHandler
+func NewListenerHandler(ucase interfaces.ListenerUCase) *ListenerHandler {
+	return &ListenerHandler{
+		UCase: ucase,
+	}
+}
+
+// GetListeners lists the event listeners and their progress.
+// @Summary List event listeners
+// @Description This admin endpoint returns every event listener with the next block it processes, the chain head, its lag behind the chain head and the last error that held it back.
+// @Tags listener
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} dto.ListenerStatusDTO "Event listeners"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/listeners [get]
+func (h *ListenerHandler) GetListeners(ctx *gin.Context) {
+	listeners, err := h.UCase.GetListeners(ctx)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve event listeners: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listeners)
+}
+
+// PauseListener pauses an event listener.
+// @Summary Pause an event listener
+// @Description This admin endpoint stops an event listener from processing blocks once the block chunk being processed is done. The listener stays paused until it is resumed or the service restarts.
+// @Tags listener
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Listener name, e.g. membership or token"
+// @Success 200 {object} dto.ListenerStatusDTO "Listener paused"
+// @Failure 404 {object} util.GeneralError "Listener not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/listeners/{name}/pause [post]
+func (h *ListenerHandler) PauseListener(ctx *gin.Context) {
+	name := ctx.Param("name")
+	listener, err := h.UCase.PauseListener(ctx, name)
+	if err != nil {
+		log.LG.Errorf("Failed to pause listener %s: %v", name, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if listener == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Listener not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listener)
+}
+
+// ResumeListener resumes a paused event listener.
+// @Summary Resume an event listener
+// @Description This admin endpoint lets a paused event listener process blocks again, from where it stopped.
+// @Tags listener
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Listener name, e.g. membership or token"
+// @Success 200 {object} dto.ListenerStatusDTO "Listener resumed"
+// @Failure 404 {object} util.GeneralError "Listener not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/listeners/{name}/resume [post]
+func (h *ListenerHandler) ResumeListener(ctx *gin.Context) {
+	name := ctx.Param("name")
+	listener, err := h.UCase.ResumeListener(ctx, name)
+	if err != nil {
+		log.LG.Errorf("Failed to resume listener %s: %v", name, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if listener == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Listener not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listener)
+}
+
+// RewindListener rewinds an event listener to a block.
+// @Summary Rewind an event listener
+// @Description This admin endpoint makes an event listener process the blocks again from the given block, which must not be after the next block it processes. The rewind is applied before the next block chunk, even while the listener is paused, and is reported as pending until then. Events indexed again are skipped, and only the events that were missed are indexed and published.
+// @Tags listener
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Listener name, e.g. membership or token"
+// @Param payload body dto.RewindListenerPayloadDTO true "Block to process again from"
+// @Success 202 {object} dto.ListenerStatusDTO "Rewind accepted"
+// @Failure 400 {object} util.GeneralError "Invalid payload or block"
+// @Failure 404 {object} util.GeneralError "Listener not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/listeners/{name}/rewind [post]
+func (h *ListenerHandler) RewindListener(ctx *gin.Context) {
+	var req dto.RewindListenerPayloadDTO
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := ctx.Param("name")
+	listener, err := h.UCase.RewindListener(ctx, name, req.Block)
+	if err != nil {
+		if errors.Is(err, blockchain.ErrInvalidRewindBlock) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid block",
+				"details": err.Error(),
+			})
+			return
+		}
+		log.LG.Errorf("Failed to rewind listener %s: %v", name, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if listener == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Listener not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, listener)
+}
+
+// ResetListener resets an event listener to its start block.
+// @Summary Reset an event listener
+// @Description This admin endpoint makes an event listener start over from its configured start block, forgetting its block cursor, the block hashes kept for reorg detection and the events kept for retraction. The reset is applied before the next block chunk, even while the listener is paused, and is reported as pending until then. Events indexed again are skipped, and only the events that were missed are indexed and published.
+// @Tags listener
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param name path string true "Listener name, e.g. membership or token"
+// @Success 202 {object} dto.ListenerStatusDTO "Reset accepted"
+// @Failure 404 {object} util.GeneralError "Listener not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/listeners/{name}/reset [post]
+func (h *ListenerHandler) ResetListener(ctx *gin.Context) {
+	name := ctx.Param("name")
+	listener, err := h.UCase.ResetListener(ctx, name)
+	if err != nil {
+		log.LG.Errorf("Failed to reset listener %s: %v", name, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if listener == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Listener not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, listener)
+}
@@ -0,0 +1,128 @@
+package listener
+
+import (
+	"context"
+
+	"github.com/genefriendway/onchain-handler/blockchain"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+type listenerUCase struct {
+	Registry *blockchain.ListenerRegistry
+}
+
+func NewListenerUCase(registry *blockchain.ListenerRegistry) interfaces.ListenerUCase {
+	return &listenerUCase{
+		Registry: registry,
+	}
+}
+
+// GetListeners retrieves the progress of every listener, with their lag behind the current chain head.
+func (u *listenerUCase) GetListeners(ctx context.Context) ([]dto.ListenerStatusDTO, error) {
+	// The head seen by a paused or failing listener may be stale
+	chainHead, err := u.Registry.ETHClient.BlockNumber(ctx)
+	if err != nil {
+		log.LG.Warnf("Failed to retrieve the latest block number, reporting the last one seen by the listeners: %v", err)
+		chainHead = 0
+	}
+
+	statuses := []dto.ListenerStatusDTO{}
+	for _, listener := range u.Registry.Listeners {
+		statuses = append(statuses, toListenerStatusDTO(listener, chainHead))
+	}
+	return statuses, nil
+}
+
+// PauseListener stops a listener from processing blocks, or returns nil if there is no such listener.
+func (u *listenerUCase) PauseListener(ctx context.Context, name string) (*dto.ListenerStatusDTO, error) {
+	listener := u.Registry.Listener(name)
+	if listener == nil {
+		return nil, nil
+	}
+
+	listener.Pause()
+	log.LG.Infof("Listener %s paused", listener.Key)
+
+	status := toListenerStatusDTO(listener, 0)
+	return &status, nil
+}
+
+// ResumeListener lets a paused listener process blocks again, or returns nil if there is no such listener.
+func (u *listenerUCase) ResumeListener(ctx context.Context, name string) (*dto.ListenerStatusDTO, error) {
+	listener := u.Registry.Listener(name)
+	if listener == nil {
+		return nil, nil
+	}
+
+	listener.Resume()
+	log.LG.Infof("Listener %s resumed", listener.Key)
+
+	status := toListenerStatusDTO(listener, 0)
+	return &status, nil
+}
+
+// RewindListener makes a listener process the blocks again from the given block, or returns nil if there is no such
+// listener. The rewind is applied by the listener before its next block chunk.
+func (u *listenerUCase) RewindListener(ctx context.Context, name string, block uint64) (*dto.ListenerStatusDTO, error) {
+	listener := u.Registry.Listener(name)
+	if listener == nil {
+		return nil, nil
+	}
+
+	if err := listener.Rewind(block); err != nil {
+		return nil, err
+	}
+	log.LG.Warnf("Listener %s rewound to block %d", listener.Key, block)
+
+	status := toListenerStatusDTO(listener, 0)
+	return &status, nil
+}
+
+// ResetListener makes a listener start over from its start block, forgetting its cursor, block hashes and published
+// events, or returns nil if there is no such listener. The reset is applied by the listener before its next block chunk.
+func (u *listenerUCase) ResetListener(ctx context.Context, name string) (*dto.ListenerStatusDTO, error) {
+	listener := u.Registry.Listener(name)
+	if listener == nil {
+		return nil, nil
+	}
+
+	startBlock, err := listener.Reset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.LG.Warnf("Listener %s reset to start block %d", listener.Key, startBlock)
+
+	status := toListenerStatusDTO(listener, 0)
+	return &status, nil
+}
+
+// toListenerStatusDTO converts the status of a listener, using chainHead as the chain head unless it is 0.
+func toListenerStatusDTO(listener *blockchain.ContractEventListener, chainHead uint64) dto.ListenerStatusDTO {
+	status := listener.Status()
+	if chainHead == 0 {
+		chainHead = status.ChainHead
+	}
+
+	lag := uint64(0)
+	if chainHead >= status.CurrentBlock {
+		lag = chainHead - status.CurrentBlock + 1
+	}
+
+	return dto.ListenerStatusDTO{
+		Name:            listener.Name,
+		ChainID:         status.Key.ChainID,
+		ContractAddress: status.Key.ContractAddress,
+		EventNames:      status.Key.EventNames,
+		Running:         status.Running,
+		Paused:          status.Paused,
+		CurrentBlock:    status.CurrentBlock,
+		ChainHead:       chainHead,
+		Lag:             lag,
+		PendingRewind:   status.PendingRewind,
+		PendingReset:    status.PendingReset,
+		LastError:       status.LastError,
+		LastErrorAt:     status.LastErrorAt,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
@@ -70,6 +71,42 @@ func (h *MembershipHandler) GetMembershipEventsByOrderIDs(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, events)
 }
 
+// GetMembershipStatus retrieves the membership status of a user.
+// @Summary Retrieve the membership status of a user
+// @Description This endpoint returns whether the user's membership is currently active and when it expires, computed from the indexed purchases with renewals stacked, along with the purchase history. With verify=true, the status is cross-checked against the membership contract.
+// @Tags membership
+// @Accept json
+// @Produce json
+// @Param address path string true "User address"
+// @Param verify query bool false "Cross-check the status against the membership contract"
+// @Success 200 {object} dto.MembershipStatusDTO "Successful retrieval of the membership status"
+// @Failure 400 {object} util.GeneralError "Invalid user address"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/membership/users/{address} [get]
+func (h *MembershipHandler) GetMembershipStatus(ctx *gin.Context) {
+	address := ctx.Param("address")
+	if !common.IsHexAddress(address) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user address"})
+		return
+	}
+
+	verifyOnChain, err := strconv.ParseBool(ctx.DefaultQuery("verify", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "verify must be a boolean"})
+		return
+	}
+
+	// Addresses are indexed in their checksum form
+	status, err := h.UCase.GetMembershipStatus(ctx, common.HexToAddress(address).Hex(), verifyOnChain)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve membership status of %s: %v", address, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, status)
+}
+
 // parseOrderIDs parses a comma-separated string of order IDs into a slice of uint64.
 func parseOrderIDs(orderIDsStr string) ([]uint64, error) {
 	var orderIDs []uint64
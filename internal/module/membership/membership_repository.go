@@ -3,11 +3,15 @@ package membership
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/genefriendway/onchain-handler/internal/constants"
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
 	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
 )
 
 type membershipRepository struct {
@@ -20,16 +24,42 @@ func NewMembershipRepository(db *gorm.DB) interfaces.MembershipRepository {
 	}
 }
 
+// CreateMembershipEventHistory stores a membership event. An event previously orphaned by a chain reorg
+// is revived with the new block data when the same transaction is re-included in the canonical chain.
 func (r *membershipRepository) CreateMembershipEventHistory(ctx context.Context, membershipEvent model.MembershipEvent) error {
-	if err := r.db.WithContext(ctx).Create(&membershipEvent).Error; err != nil {
-		return err
+	result := unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "transaction_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "end_duration", "duration", "block_number", "block_hash", "block_timestamp", "log_index", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Eq{Column: clause.Column{Table: membershipEvent.TableName(), Name: "status"}, Value: constants.MembershipEventOrphaned},
+			}},
+		}).
+		Create(&membershipEvent)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
 	}
 	return nil
 }
 
+// MarkMembershipEventsOrphanedFromBlock marks all events included at or above the given block number as orphaned.
+func (r *membershipRepository) MarkMembershipEventsOrphanedFromBlock(ctx context.Context, blockNumber uint64) (int64, error) {
+	result := unitofwork.DB(ctx, r.db).
+		Model(&model.MembershipEvent{}).
+		Where("block_number >= ? AND status <> ?", blockNumber, constants.MembershipEventOrphaned).
+		Update("status", constants.MembershipEventOrphaned)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
 func (r *membershipRepository) GetMembershipEventByOrderID(ctx context.Context, orderID uint64) (*model.MembershipEvent, error) {
 	var membershipEvent model.MembershipEvent
-	if err := r.db.WithContext(ctx).Where("order_id = ?", orderID).First(&membershipEvent).Error; err != nil {
+	if err := unitofwork.DB(ctx, r.db).Where("order_id = ?", orderID).First(&membershipEvent).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -46,7 +76,7 @@ func (r *membershipRepository) GetMembershipEventsByOrderIDs(ctx context.Context
 	}
 
 	// Query the database to get all events matching the given order IDs.
-	if err := r.db.WithContext(ctx).
+	if err := unitofwork.DB(ctx, r.db).
 		Where("order_id IN ?", orderIDs).
 		Find(&membershipEvents).Error; err != nil {
 		return nil, err
@@ -54,3 +84,68 @@ func (r *membershipRepository) GetMembershipEventsByOrderIDs(ctx context.Context
 
 	return membershipEvents, nil
 }
+
+// GetMembershipEventsByUserAddress retrieves the membership purchases of a user that were not orphaned by a reorg,
+// in the order they were made.
+func (r *membershipRepository) GetMembershipEventsByUserAddress(ctx context.Context, userAddress string) ([]model.MembershipEvent, error) {
+	var membershipEvents []model.MembershipEvent
+	if err := unitofwork.DB(ctx, r.db).
+		Where("user_address = ? AND status <> ?", userAddress, constants.MembershipEventOrphaned).
+		Order("block_number ASC, log_index ASC, id ASC").
+		Find(&membershipEvents).Error; err != nil {
+		return nil, err
+	}
+
+	return membershipEvents, nil
+}
+
+// GetUserAddressesWithMembershipsEndingBefore retrieves, in address order and after the given address,
+// the users whose successful purchases all end before the given time. Users with a purchase ending later,
+// such as a renewal, cannot have a membership ending before the given time and are left out.
+func (r *membershipRepository) GetUserAddressesWithMembershipsEndingBefore(ctx context.Context, before time.Time, afterAddress string, limit int) ([]string, error) {
+	var userAddresses []string
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.MembershipEvent{}).
+		Where("status = ? AND user_address > ?", constants.MembershipEventSuccess, afterAddress).
+		Group("user_address").
+		Having("MAX(end_duration) <= ?", before).
+		Order("user_address ASC").
+		Limit(limit).
+		Pluck("user_address", &userAddresses).Error; err != nil {
+		return nil, err
+	}
+
+	return userAddresses, nil
+}
+
+// ExpireMembershipEvents marks the successful purchases of a user as expired.
+func (r *membershipRepository) ExpireMembershipEvents(ctx context.Context, userAddress string) (int64, error) {
+	result := unitofwork.DB(ctx, r.db).
+		Model(&model.MembershipEvent{}).
+		Where("user_address = ? AND status = ?", userAddress, constants.MembershipEventSuccess).
+		Update("status", constants.MembershipEventExpired)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// HasMembershipNotification checks whether the given notification was already delivered.
+func (r *membershipRepository) HasMembershipNotification(ctx context.Context, notification model.MembershipNotification) (bool, error) {
+	var count int64
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.MembershipNotification{}).
+		Where("user_address = ? AND notification_type = ? AND expires_at = ? AND lead_time = ?",
+			notification.UserAddress, notification.NotificationType, notification.ExpiresAt, notification.LeadTime).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateMembershipNotification records a delivered notification. Recording it twice is not an error.
+func (r *membershipRepository) CreateMembershipNotification(ctx context.Context, notification model.MembershipNotification) error {
+	return unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&notification).Error
+}
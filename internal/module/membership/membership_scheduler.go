@@ -0,0 +1,50 @@
+package membership
+
+import (
+	"context"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// DefaultMembershipExpiryInterval is the delay between two checks of the memberships expiring.
+const DefaultMembershipExpiryInterval = 5 * time.Minute
+
+// MembershipExpiryScheduler periodically expires memberships and sends the expiry notifications.
+type MembershipExpiryScheduler struct {
+	UCase    interfaces.MembershipUCase
+	Interval time.Duration
+}
+
+// NewMembershipExpiryScheduler initializes the membership expiry scheduler.
+func NewMembershipExpiryScheduler(ucase interfaces.MembershipUCase, interval time.Duration) *MembershipExpiryScheduler {
+	if interval <= 0 {
+		interval = DefaultMembershipExpiryInterval
+	}
+	return &MembershipExpiryScheduler{
+		UCase:    ucase,
+		Interval: interval,
+	}
+}
+
+// Run checks the memberships expiring on every interval until the context is cancelled.
+func (scheduler *MembershipExpiryScheduler) Run(ctx context.Context) {
+	log.LG.Infof("Starting membership expiry scheduler, checking every %s...", scheduler.Interval)
+
+	ticker := time.NewTicker(scheduler.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := scheduler.UCase.ProcessMembershipExpiries(ctx); err != nil {
+			log.LG.Errorf("Failed to process membership expiries: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.LG.Info("Membership expiry scheduler stopped.")
+			return
+		}
+	}
+}
@@ -2,18 +2,55 @@ package membership
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/genefriendway/onchain-handler/blockchain"
+	"github.com/genefriendway/onchain-handler/internal/constants"
 	"github.com/genefriendway/onchain-handler/internal/dto"
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
 )
 
+// membershipExpiryBatchSize is the number of users whose memberships are checked per query by the expiry scheduler.
+const membershipExpiryBatchSize = 100
+
+// DefaultMembershipExpiryLeadTimes are the times before expiry at which users are notified that their membership expires soon.
+var DefaultMembershipExpiryLeadTimes = []time.Duration{7 * 24 * time.Hour, 24 * time.Hour}
+
 type membershipUCase struct {
 	MembershipRepository interfaces.MembershipRepository
+	MembershipContract   *blockchain.MembershipContract
+	Notifier             interfaces.MembershipNotifier
+	ExpiryLeadTimes      []time.Duration // Sorted from the shortest to the longest
 }
 
-func NewMembershipUCase(membershipRepository interfaces.MembershipRepository) interfaces.MembershipUCase {
+func NewMembershipUCase(
+	membershipRepository interfaces.MembershipRepository,
+	membershipContract *blockchain.MembershipContract,
+	notifier interfaces.MembershipNotifier,
+	expiryLeadTimes []time.Duration,
+) interfaces.MembershipUCase {
+	if len(expiryLeadTimes) == 0 {
+		expiryLeadTimes = DefaultMembershipExpiryLeadTimes
+	}
+	leadTimes := make([]time.Duration, 0, len(expiryLeadTimes))
+	for _, leadTime := range expiryLeadTimes {
+		if leadTime > 0 {
+			leadTimes = append(leadTimes, leadTime)
+		}
+	}
+	sort.Slice(leadTimes, func(i, j int) bool { return leadTimes[i] < leadTimes[j] })
+
 	return &membershipUCase{
 		MembershipRepository: membershipRepository,
+		MembershipContract:   membershipContract,
+		Notifier:             notifier,
+		ExpiryLeadTimes:      leadTimes,
 	}
 }
 
@@ -51,3 +88,169 @@ func (u *membershipUCase) GetMembershipEventsByOrderIDs(ctx context.Context, ord
 
 	return membershipEventDTOs, nil
 }
+
+// GetMembershipStatus computes the membership status of a user from the indexed purchases. A renewal bought before
+// the current membership expires extends it from its expiry, so durations stack. When verifyOnChain is set,
+// the status is cross-checked against the view functions of the membership contract.
+func (u *membershipUCase) GetMembershipStatus(ctx context.Context, userAddress string, verifyOnChain bool) (*dto.MembershipStatusDTO, error) {
+	membershipEvents, err := u.MembershipRepository.GetMembershipEventsByUserAddress(ctx, userAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &dto.MembershipStatusDTO{
+		UserAddress: userAddress,
+		Purchases:   []dto.MembershipEventDTO{},
+	}
+
+	for _, event := range membershipEvents {
+		status.Purchases = append(status.Purchases, event.ToDto())
+	}
+
+	expiresAt := membershipExpiry(membershipEvents)
+	if !expiresAt.IsZero() {
+		status.ExpiresAt = &expiresAt
+		status.Active = time.Now().Before(expiresAt)
+	}
+
+	if verifyOnChain {
+		onChain, err := u.MembershipContract.GetMembership(ctx, common.HexToAddress(userAddress))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read membership from contract: %w", err)
+		}
+		status.OnChain = &dto.OnChainMembershipDTO{
+			Active:     onChain.Active,
+			IsMember:   onChain.IsMember,
+			Expiration: onChain.Expiration,
+			Consistent: onChain.Active == status.Active,
+		}
+	}
+
+	return status, nil
+}
+
+// ProcessMembershipExpiries marks the purchases of users whose membership expired as expired, and notifies users
+// whose membership expired or expires within one of the lead times. Each notification is sent once per expiry,
+// so a renewal makes the notifications of the new expiry due again.
+func (u *membershipUCase) ProcessMembershipExpiries(ctx context.Context) error {
+	now := time.Now()
+	horizon := now
+	if len(u.ExpiryLeadTimes) > 0 {
+		horizon = now.Add(u.ExpiryLeadTimes[len(u.ExpiryLeadTimes)-1])
+	}
+
+	// The candidates are the users whose purchases all end before the horizon. Their membership may still end
+	// after it, since renewals stack, so the expiry is computed from all the purchases of each candidate user.
+	afterAddress := ""
+	for {
+		userAddresses, err := u.MembershipRepository.GetUserAddressesWithMembershipsEndingBefore(ctx, horizon, afterAddress, membershipExpiryBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get users with expiring memberships: %w", err)
+		}
+
+		for _, userAddress := range userAddresses {
+			if err := u.processMembershipExpiry(ctx, userAddress, now); err != nil {
+				log.LG.Errorf("Failed to process membership expiry of %s: %v", userAddress, err)
+			}
+		}
+
+		if len(userAddresses) < membershipExpiryBatchSize {
+			return nil
+		}
+		afterAddress = userAddresses[len(userAddresses)-1]
+	}
+}
+
+func (u *membershipUCase) processMembershipExpiry(ctx context.Context, userAddress string, now time.Time) error {
+	membershipEvents, err := u.MembershipRepository.GetMembershipEventsByUserAddress(ctx, userAddress)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := membershipExpiry(membershipEvents)
+	if expiresAt.IsZero() {
+		return nil
+	}
+
+	if !now.Before(expiresAt) {
+		// Notify before expiring the purchases, which removes the user from the candidates of the next runs
+		err := u.notifyOnce(ctx, model.MembershipNotification{
+			UserAddress:      userAddress,
+			NotificationType: constants.MembershipExpired,
+			ExpiresAt:        expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		expired, err := u.MembershipRepository.ExpireMembershipEvents(ctx, userAddress)
+		if err != nil {
+			return fmt.Errorf("failed to expire membership events: %w", err)
+		}
+		log.LG.Infof("Membership of %s expired at %s, marked %d purchases as expired", userAddress, expiresAt.Format(time.RFC3339), expired)
+		return nil
+	}
+
+	// Only the shortest lead time reached is notified, so a user is not sent several notifications at once
+	// when the scheduler was not running
+	for _, leadTime := range u.ExpiryLeadTimes {
+		if expiresAt.Sub(now) <= leadTime {
+			return u.notifyOnce(ctx, model.MembershipNotification{
+				UserAddress:      userAddress,
+				NotificationType: constants.MembershipExpiringSoon,
+				ExpiresAt:        expiresAt,
+				LeadTime:         int64(leadTime / time.Second),
+			})
+		}
+	}
+	return nil
+}
+
+// notifyOnce sends the notification unless it was already delivered, and records its delivery.
+func (u *membershipUCase) notifyOnce(ctx context.Context, notification model.MembershipNotification) error {
+	delivered, err := u.MembershipRepository.HasMembershipNotification(ctx, notification)
+	if err != nil {
+		return fmt.Errorf("failed to check membership notification: %w", err)
+	}
+	if delivered {
+		return nil
+	}
+
+	payload := dto.MembershipExpiryNotificationDTO{
+		Type:        notification.NotificationType,
+		UserAddress: notification.UserAddress,
+		ExpiresAt:   notification.ExpiresAt,
+		SentAt:      time.Now(),
+	}
+	if notification.LeadTime > 0 {
+		payload.LeadTime = (time.Duration(notification.LeadTime) * time.Second).String()
+	}
+	if err := u.Notifier.NotifyMembershipExpiry(ctx, payload); err != nil {
+		return fmt.Errorf("failed to send %s notification: %w", notification.NotificationType, err)
+	}
+
+	if err := u.MembershipRepository.CreateMembershipNotification(ctx, notification); err != nil {
+		return fmt.Errorf("failed to record membership notification: %w", err)
+	}
+	return nil
+}
+
+// membershipExpiry computes the expiry of a membership from its purchases, in the order they were made.
+// A renewal bought before the membership expires extends it from its expiry. It returns the zero time
+// when there is no purchase.
+func membershipExpiry(membershipEvents []model.MembershipEvent) time.Time {
+	var expiresAt time.Time
+	for _, event := range membershipEvents {
+		durationDays, ok := constants.MembershipDurationDays[event.Duration]
+		if !ok {
+			continue
+		}
+
+		start := event.PurchasedAt()
+		if expiresAt.After(start) {
+			start = expiresAt
+		}
+		expiresAt = start.AddDate(0, 0, durationDays)
+	}
+	return expiresAt
+}
@@ -0,0 +1,57 @@
+package nonce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
+)
+
+type nonceRepository struct {
+	db *gorm.DB
+}
+
+// NewNonceRepository creates a new NonceRepository
+func NewNonceRepository(db *gorm.DB) interfaces.NonceRepository {
+	return &nonceRepository{
+		db: db,
+	}
+}
+
+// GetNextNonce retrieves the stored next nonce of the account, or 0 if none was stored yet.
+func (r *nonceRepository) GetNextNonce(ctx context.Context, chainID uint64, address string) (uint64, error) {
+	var signerNonce model.SignerNonce
+	if err := unitofwork.DB(ctx, r.db).
+		Where("chain_id = ? AND address = ?", chainID, address).
+		First(&signerNonce).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get next nonce of %s: %w", address, err)
+	}
+	return signerNonce.NextNonce, nil
+}
+
+// SaveNextNonce stores the next nonce of the account.
+func (r *nonceRepository) SaveNextNonce(ctx context.Context, chainID uint64, address string, nextNonce uint64) error {
+	signerNonce := model.SignerNonce{
+		ChainID:   chainID,
+		Address:   address,
+		NextNonce: nextNonce,
+	}
+	if err := unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chain_id"}, {Name: "address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"next_nonce"}),
+		}).
+		Create(&signerNonce).Error; err != nil {
+		return fmt.Errorf("failed to save next nonce of %s: %w", address, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package token
+
+import (
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/middleware"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+type TokenHandler struct {
+	UCase interfaces.TokenUCase
+}
+
+// NewTokenHandler initializes the TokenHandler
+func NewTokenHandler(ucase interfaces.TokenUCase) *TokenHandler {
+	return &TokenHandler{
+		UCase: ucase,
+	}
+}
+
+// GetLedgerBalance retrieves the indexed LifePoint balance of an address.
+// @Summary Retrieve the indexed token balance of an address
+// @Description This endpoint returns the LifePoint balance of an address in the smallest unit of the token, computed from the Transfer events indexed so far, and the block of the last transfer applied to it.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param address path string true "Address"
+// @Success 200 {object} dto.TokenBalanceDTO "Indexed balance"
+// @Failure 400 {object} util.GeneralError "Invalid address"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/ledger/{address} [get]
+func (h *TokenHandler) GetLedgerBalance(ctx *gin.Context) {
+	address := ctx.Param("address")
+	if !common.IsHexAddress(address) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+		return
+	}
+
+	// Addresses are indexed in their checksum form
+	balance, err := h.UCase.GetLedgerBalance(ctx, common.HexToAddress(address).Hex())
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve indexed balance of %s: %v", address, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balance)
+}
+
+// GetTokenTransfers retrieves the indexed LifePoint transfers.
+// @Summary Retrieve indexed token transfers
+// @Description This endpoint returns the LifePoint transfers indexed from the Transfer events of the token, newest first, optionally only those from or to an address.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param size query int false "Page size, between 4 and 50" default(10)
+// @Param address query string false "Sender or recipient address"
+// @Success 200 {object} dto.PaginationDTOResponse{data=[]dto.TokenTransferDTO} "Indexed transfers"
+// @Failure 400 {object} util.GeneralError "Invalid address"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/transfers [get]
+func (h *TokenHandler) GetTokenTransfers(ctx *gin.Context) {
+	address := ctx.Query("address")
+	if address != "" {
+		if !common.IsHexAddress(address) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+			return
+		}
+		address = common.HexToAddress(address).Hex()
+	}
+
+	page := ctx.GetInt(middleware.DEFAULT_PAGE_TEXT)
+	size := ctx.GetInt(middleware.DEFAULT_SIZE_TEXT)
+	response, err := h.UCase.GetTokenTransfers(ctx, address, page, size)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve token transfers: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetOnChainBalance retrieves the LifePoint balance of an address from the token contract.
+// @Summary Retrieve the on-chain token balance of an address
+// @Description This endpoint returns the LifePoint balance of an address in the smallest unit of the token, as read from the token contract. Balances are cached for a few seconds.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param address path string true "Address"
+// @Success 200 {object} dto.TokenOnChainBalanceDTO "On-chain balance"
+// @Failure 400 {object} util.GeneralError "Invalid address"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/balances/{address} [get]
+func (h *TokenHandler) GetOnChainBalance(ctx *gin.Context) {
+	address := ctx.Param("address")
+	if !common.IsHexAddress(address) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address"})
+		return
+	}
+
+	balance, err := h.UCase.GetOnChainBalance(ctx, common.HexToAddress(address).Hex())
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve on-chain balance of %s: %v", address, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balance)
+}
+
+// GetRewardBalance retrieves the LifePoint balance of the reward wallet.
+// @Summary Retrieve the token balance of the reward wallet
+// @Description This endpoint returns the LifePoint balance of the wallet the rewards are paid from, as read from the token contract. Balances are cached for a few seconds.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.TokenOnChainBalanceDTO "On-chain balance"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/reward/balance [get]
+func (h *TokenHandler) GetRewardBalance(ctx *gin.Context) {
+	balance, err := h.UCase.GetRewardBalance(ctx)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve balance of the reward wallet: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balance)
+}
+
+// GetMembershipContractBalance retrieves the LifePoint balance of the membership contract.
+// @Summary Retrieve the token balance of the membership contract
+// @Description This endpoint returns the LifePoint balance of the membership contract, which receives the membership payments, as read from the token contract. Balances are cached for a few seconds.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.TokenOnChainBalanceDTO "On-chain balance"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/membership/balance [get]
+func (h *TokenHandler) GetMembershipContractBalance(ctx *gin.Context) {
+	balance, err := h.UCase.GetMembershipContractBalance(ctx)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve balance of the membership contract: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, balance)
+}
+
+// GetTotalSupply retrieves the total supply of LifePoint.
+// @Summary Retrieve the token total supply
+// @Description This endpoint returns the total supply of LifePoint in the smallest unit of the token, as read from the token contract. The supply is cached for a few seconds.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.TokenSupplyDTO "Total supply"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/supply [get]
+func (h *TokenHandler) GetTotalSupply(ctx *gin.Context) {
+	supply, err := h.UCase.GetTotalSupply(ctx)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve total supply: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, supply)
+}
+
+// GetAllowance retrieves the LifePoint allowance of a spender.
+// @Summary Retrieve a token allowance
+// @Description This endpoint returns the amount of LifePoint the spender may still transfer on behalf of the owner, in the smallest unit of the token, as read from the token contract. Allowances are cached for a few seconds.
+// @Tags token
+// @Accept json
+// @Produce json
+// @Param owner query string true "Owner address"
+// @Param spender query string true "Spender address"
+// @Success 200 {object} dto.TokenAllowanceDTO "Allowance"
+// @Failure 400 {object} util.GeneralError "Invalid owner or spender address"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/token/allowance [get]
+func (h *TokenHandler) GetAllowance(ctx *gin.Context) {
+	owner := ctx.Query("owner")
+	if !common.IsHexAddress(owner) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid owner address"})
+		return
+	}
+	spender := ctx.Query("spender")
+	if !common.IsHexAddress(spender) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spender address"})
+		return
+	}
+
+	allowance, err := h.UCase.GetAllowance(ctx, common.HexToAddress(owner).Hex(), common.HexToAddress(spender).Hex())
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve allowance of %s for %s: %v", spender, owner, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, allowance)
+}
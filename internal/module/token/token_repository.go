@@ -0,0 +1,154 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
+)
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) interfaces.TokenRepository {
+	return &tokenRepository{
+		db: db,
+	}
+}
+
+// CreateTokenTransfer stores a token transfer. A transfer previously orphaned by a chain reorg is revived
+// with the new block data when the same log is re-included in the canonical chain.
+// It returns gorm.ErrDuplicatedKey when the transfer is already stored.
+func (r *tokenRepository) CreateTokenTransfer(ctx context.Context, transfer model.TokenTransfer) error {
+	result := unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "transaction_hash"}, {Name: "log_index"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "block_number", "block_hash", "block_timestamp", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Eq{Column: clause.Column{Table: transfer.TableName(), Name: "status"}, Value: constants.TokenTransferOrphaned},
+			}},
+		}).
+		Create(&transfer)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+// GetTokenTransfersByTxHash retrieves the token transfers of a transaction that were not orphaned, in log order.
+func (r *tokenRepository) GetTokenTransfersByTxHash(ctx context.Context, txHash string) ([]model.TokenTransfer, error) {
+	var transfers []model.TokenTransfer
+	if err := unitofwork.DB(ctx, r.db).
+		Where("transaction_hash = ? AND status = ?", txHash, constants.TokenTransferSuccess).
+		Order("log_index ASC").
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// GetTokenTransfersFromBlock retrieves the token transfers included at or above the given block that were not orphaned.
+func (r *tokenRepository) GetTokenTransfersFromBlock(ctx context.Context, tokenAddress string, blockNumber uint64) ([]model.TokenTransfer, error) {
+	var transfers []model.TokenTransfer
+	if err := unitofwork.DB(ctx, r.db).
+		Where("token_address = ? AND block_number >= ? AND status = ?", tokenAddress, blockNumber, constants.TokenTransferSuccess).
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+// MarkTokenTransfersOrphanedFromBlock marks all token transfers included at or above the given block number as orphaned.
+func (r *tokenRepository) MarkTokenTransfersOrphanedFromBlock(ctx context.Context, tokenAddress string, blockNumber uint64) (int64, error) {
+	result := unitofwork.DB(ctx, r.db).
+		Model(&model.TokenTransfer{}).
+		Where("token_address = ? AND block_number >= ? AND status = ?", tokenAddress, blockNumber, constants.TokenTransferSuccess).
+		Update("status", constants.TokenTransferOrphaned)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// GetTokenTransfers retrieves a page of the token transfers from or to an address that were not orphaned,
+// newest first, together with their total number. All transfers are retrieved when address is empty.
+func (r *tokenRepository) GetTokenTransfers(ctx context.Context, tokenAddress, address string, limit, offset int) ([]model.TokenTransfer, int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("token_address = ? AND status = ?", tokenAddress, constants.TokenTransferSuccess)
+		if address != "" {
+			db = db.Where("from_address = ? OR to_address = ?", address, address)
+		}
+		return db
+	}
+
+	var total int64
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.TokenTransfer{}).
+		Scopes(filter).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []model.TokenTransfer
+	if err := unitofwork.DB(ctx, r.db).
+		Scopes(filter).
+		Order("block_number DESC, log_index DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&transfers).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return transfers, total, nil
+}
+
+// AddTokenBalance adds delta, which may be negative, to the balance of an address.
+func (r *tokenRepository) AddTokenBalance(ctx context.Context, tokenAddress, address string, delta *big.Int, blockNumber uint64) error {
+	balance := model.TokenBalance{
+		TokenAddress: tokenAddress,
+		Address:      address,
+		Balance:      delta.String(),
+		LastBlock:    blockNumber,
+		UpdatedAt:    time.Now(),
+	}
+	if err := unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "token_address"}, {Name: "address"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"balance":    gorm.Expr("token_balance.balance + EXCLUDED.balance"),
+				"last_block": gorm.Expr("GREATEST(token_balance.last_block, EXCLUDED.last_block)"),
+				"updated_at": gorm.Expr("EXCLUDED.updated_at"),
+			}),
+		}).
+		Create(&balance).Error; err != nil {
+		return fmt.Errorf("failed to update token balance of %s: %w", address, err)
+	}
+	return nil
+}
+
+// GetTokenBalance retrieves the indexed balance of an address, or nil if no transfer of the address was indexed.
+func (r *tokenRepository) GetTokenBalance(ctx context.Context, tokenAddress, address string) (*model.TokenBalance, error) {
+	var balance model.TokenBalance
+	if err := unitofwork.DB(ctx, r.db).
+		Where("token_address = ? AND address = ?", tokenAddress, address).
+		First(&balance).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &balance, nil
+}
@@ -0,0 +1,217 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/genefriendway/onchain-handler/blockchain"
+	"github.com/genefriendway/onchain-handler/conf"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	// DefaultTokenCacheTTL is the time the values read from the token contract are cached for.
+	DefaultTokenCacheTTL = 15 * time.Second
+	tokenCacheKeyPrefix  = "onchain-handler:token"
+)
+
+type tokenUCase struct {
+	TokenRepository           interfaces.TokenRepository
+	TokenContract             *blockchain.TokenContract
+	Cache                     *redis.Client // Values read from the token contract are not cached when nil
+	CacheTTL                  time.Duration
+	TokenAddress              string
+	RewardAddress             string
+	MembershipContractAddress string
+}
+
+func NewTokenUCase(
+	tokenRepository interfaces.TokenRepository,
+	tokenContract *blockchain.TokenContract,
+	cache *redis.Client,
+	config *conf.Configuration,
+) interfaces.TokenUCase {
+	cacheTTL := config.TokenCacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = DefaultTokenCacheTTL
+	}
+
+	return &tokenUCase{
+		TokenRepository:           tokenRepository,
+		TokenContract:             tokenContract,
+		Cache:                     cache,
+		CacheTTL:                  cacheTTL,
+		TokenAddress:              common.HexToAddress(config.Blockchain.LifePointAddress).Hex(),
+		RewardAddress:             common.HexToAddress(config.Blockchain.RewardAddress).Hex(),
+		MembershipContractAddress: common.HexToAddress(config.Blockchain.MembershipContractAddress).Hex(),
+	}
+}
+
+// GetLedgerBalance retrieves the balance of an address computed from the indexed transfers.
+// An address without indexed transfers has a zero balance.
+func (u *tokenUCase) GetLedgerBalance(ctx context.Context, address string) (*dto.TokenBalanceDTO, error) {
+	balance, err := u.TokenRepository.GetTokenBalance(ctx, u.TokenAddress, address)
+	if err != nil {
+		return nil, err
+	}
+	if balance == nil {
+		return &dto.TokenBalanceDTO{
+			TokenAddress: u.TokenAddress,
+			Address:      address,
+			Balance:      "0",
+		}, nil
+	}
+
+	balanceDTO := balance.ToDto()
+	return &balanceDTO, nil
+}
+
+// GetTokenTransfers retrieves a page of the indexed transfers from or to an address, newest first.
+func (u *tokenUCase) GetTokenTransfers(ctx context.Context, address string, page, size int) (dto.PaginationDTOResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	transfers, total, err := u.TokenRepository.GetTokenTransfers(ctx, u.TokenAddress, address, size, (page-1)*size)
+	if err != nil {
+		return dto.PaginationDTOResponse{}, err
+	}
+
+	transferDTOs := []dto.TokenTransferDTO{}
+	for _, transfer := range transfers {
+		transferDTOs = append(transferDTOs, transfer.ToDto())
+	}
+
+	nextPage := 0
+	if int64(page*size) < total {
+		nextPage = page + 1
+	}
+
+	return dto.PaginationDTOResponse{
+		Page:     page,
+		Size:     size,
+		Total:    total,
+		NextPage: nextPage,
+		Data:     transferDTOs,
+	}, nil
+}
+
+// GetOnChainBalance retrieves the balance of an address from the token contract.
+func (u *tokenUCase) GetOnChainBalance(ctx context.Context, address string) (*dto.TokenOnChainBalanceDTO, error) {
+	var balance dto.TokenOnChainBalanceDTO
+	key := fmt.Sprintf("%s:%s:balance:%s", tokenCacheKeyPrefix, u.TokenAddress, address)
+	err := u.cached(ctx, key, &balance, func() error {
+		amount, err := u.TokenContract.BalanceOf(ctx, common.HexToAddress(address))
+		if err != nil {
+			return err
+		}
+		balance = dto.TokenOnChainBalanceDTO{
+			TokenAddress: u.TokenAddress,
+			Address:      address,
+			Balance:      amount.String(),
+			FetchedAt:    time.Now().UTC(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// GetRewardBalance retrieves the balance of the reward wallet the transfers are paid from.
+func (u *tokenUCase) GetRewardBalance(ctx context.Context) (*dto.TokenOnChainBalanceDTO, error) {
+	return u.GetOnChainBalance(ctx, u.RewardAddress)
+}
+
+// GetMembershipContractBalance retrieves the balance of the membership contract, which receives the membership payments.
+func (u *tokenUCase) GetMembershipContractBalance(ctx context.Context) (*dto.TokenOnChainBalanceDTO, error) {
+	return u.GetOnChainBalance(ctx, u.MembershipContractAddress)
+}
+
+// GetTotalSupply retrieves the total supply of the token from the token contract.
+func (u *tokenUCase) GetTotalSupply(ctx context.Context) (*dto.TokenSupplyDTO, error) {
+	var supply dto.TokenSupplyDTO
+	key := fmt.Sprintf("%s:%s:supply", tokenCacheKeyPrefix, u.TokenAddress)
+	err := u.cached(ctx, key, &supply, func() error {
+		amount, err := u.TokenContract.TotalSupply(ctx)
+		if err != nil {
+			return err
+		}
+		supply = dto.TokenSupplyDTO{
+			TokenAddress: u.TokenAddress,
+			TotalSupply:  amount.String(),
+			FetchedAt:    time.Now().UTC(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &supply, nil
+}
+
+// GetAllowance retrieves the amount the spender may transfer on behalf of the owner from the token contract.
+func (u *tokenUCase) GetAllowance(ctx context.Context, owner, spender string) (*dto.TokenAllowanceDTO, error) {
+	var allowance dto.TokenAllowanceDTO
+	key := fmt.Sprintf("%s:%s:allowance:%s:%s", tokenCacheKeyPrefix, u.TokenAddress, owner, spender)
+	err := u.cached(ctx, key, &allowance, func() error {
+		amount, err := u.TokenContract.Allowance(ctx, common.HexToAddress(owner), common.HexToAddress(spender))
+		if err != nil {
+			return err
+		}
+		allowance = dto.TokenAllowanceDTO{
+			TokenAddress: u.TokenAddress,
+			Owner:        owner,
+			Spender:      spender,
+			Allowance:    amount.String(),
+			FetchedAt:    time.Now().UTC(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &allowance, nil
+}
+
+// cached decodes the value cached under key into out, or calls fetch to fill out and caches the result.
+// Cache failures are logged and the value is read from the chain, so that Redis being down does not
+// take the endpoints down with it.
+func (u *tokenUCase) cached(ctx context.Context, key string, out interface{}, fetch func() error) error {
+	if u.Cache == nil {
+		return fetch()
+	}
+
+	value, err := u.Cache.Get(ctx, key).Bytes()
+	if err == nil {
+		decodeErr := json.Unmarshal(value, out)
+		if decodeErr == nil {
+			return nil
+		}
+		log.LG.Warnf("Failed to decode cached value of %s: %v", key, decodeErr)
+	} else if !errors.Is(err, redis.Nil) {
+		log.LG.Warnf("Failed to read %s from cache: %v", key, err)
+	}
+
+	if err := fetch(); err != nil {
+		return err
+	}
+
+	value, err = json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	if err := u.Cache.Set(ctx, key, value, u.CacheTTL).Err(); err != nil {
+		log.LG.Warnf("Failed to cache %s: %v", key, err)
+	}
+	return nil
+}
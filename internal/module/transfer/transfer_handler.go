@@ -1,16 +1,28 @@
 package transfer
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
+	"github.com/genefriendway/onchain-handler/blockchain"
+	"github.com/genefriendway/onchain-handler/internal/constants"
 	"github.com/genefriendway/onchain-handler/internal/dto"
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/middleware"
 	"github.com/genefriendway/onchain-handler/internal/utils/log"
 )
 
+// MaxIdempotencyKeyLength is the maximum length of the Idempotency-Key header.
+const MaxIdempotencyKeyLength = 255
+
 type TransferHandler struct {
 	UCase interfaces.TransferUCase
 }
@@ -22,16 +34,18 @@ func NewTransferHandler(ucase interfaces.TransferUCase) *TransferHandler {
 	}
 }
 
-// Transfer handles the distribution of tokens to recipients.
+// Transfer queues the distribution of tokens to recipients.
 // @Summary Distribute tokens to recipients
-// @Description This endpoint allows the distribution of tokens to multiple recipients. It accepts a list of transfer requests, validates the payload, and processes the token transfers based on the transaction type.
+// @Description This endpoint allows the distribution of tokens to multiple recipients. It accepts a list of transfer requests, validates the payload, and queues a transfer job that distributes the tokens in the background. The job status can be followed with /api/v1/transfer/jobs/{id}.
 // @Tags transfer
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "Key identifying the request. A retry with the same key returns the original job instead of queueing a new one."
 // @Param payload body []dto.TransferTokenPayloadDTO true "List of transfer requests. Each request must include recipient address and transaction type."
-// @Success 200 {object} map[string]bool "Success response: {\"success\": true}"
+// @Success 202 {object} dto.TransferJobDTO "Queued transfer job"
 // @Failure 400 {object} util.GeneralError "Invalid payload or invalid recipient address/transaction type"
-// @Failure 500 {object} util.GeneralError "Internal server error, failed to distribute tokens"
+// @Failure 422 {object} util.GeneralError "The idempotency key was already used for another payload"
+// @Failure 500 {object} util.GeneralError "Internal server error, failed to queue the transfer job"
 // @Router /api/v1/transfer [post]
 func (h *TransferHandler) Transfer(ctx *gin.Context) {
 	var req []dto.TransferTokenPayloadDTO
@@ -46,6 +60,15 @@ func (h *TransferHandler) Transfer(ctx *gin.Context) {
 		return
 	}
 
+	idempotencyKey := ctx.GetHeader(constants.IdempotencyKeyHeader)
+	if len(idempotencyKey) > MaxIdempotencyKeyLength {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid idempotency key",
+			"details": fmt.Sprintf("%s must not be longer than %d characters", constants.IdempotencyKeyHeader, MaxIdempotencyKeyLength),
+		})
+		return
+	}
+
 	for _, payload := range req {
 		// Check if the recipient address is a valid Ethereum address
 		if !common.IsHexAddress(payload.RecipientAddress) {
@@ -66,15 +89,261 @@ func (h *TransferHandler) Transfer(ctx *gin.Context) {
 		}
 	}
 
-	// Proceed to distribute tokens if all checks pass
-	if err := h.UCase.DistributeTokens(ctx, req); err != nil {
-		log.LG.Errorf("Failed to distribute tokens: %v", err)
+	// Proceed to queue the distribution if all checks pass
+	job, err := h.UCase.EnqueueTransfer(ctx, idempotencyKey, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransferPayload) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid payload",
+				"details": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		log.LG.Errorf("Failed to queue transfer job: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to queue transfer job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetTransferJob retrieves the status of a transfer job.
+// @Summary Retrieve a transfer job
+// @Description This endpoint returns the status of a transfer job (0 queued, 1 processing, 2 completed, 3 failed), its attempts and the transfers created for it so far.
+// @Tags transfer
+// @Accept json
+// @Produce json
+// @Param id path string true "Transfer job ID"
+// @Success 200 {object} dto.TransferJobDTO "Successful retrieval of the transfer job"
+// @Failure 400 {object} util.GeneralError "Invalid job ID"
+// @Failure 404 {object} util.GeneralError "Transfer job not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/transfer/jobs/{id} [get]
+func (h *TransferHandler) GetTransferJob(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := h.UCase.GetTransferJob(ctx, id)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve transfer job %s: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if job == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Transfer job not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// GetTransferHistoriesByTxHash retrieves the status of the transfers sent in a reward transaction.
+// @Summary Retrieve transfers by transaction hash
+// @Description This endpoint returns the transfers sent in the given reward transaction, including their confirmation status (-1 failed, 0 pending, 1 confirmed, 2 reverted, 3 dropped) and receipt data.
+// @Tags transfer
+// @Accept json
+// @Produce json
+// @Param txHash path string true "Transaction hash"
+// @Success 200 {array} dto.TransferHistoryDTO "Successful retrieval of transfers"
+// @Failure 400 {object} util.GeneralError "Invalid transaction hash"
+// @Failure 404 {object} util.GeneralError "Transaction not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/transfer/transactions/{txHash} [get]
+func (h *TransferHandler) GetTransferHistoriesByTxHash(ctx *gin.Context) {
+	txHash := ctx.Param("txHash")
+	if len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction hash"})
+		return
+	}
+
+	transfers, err := h.UCase.GetTransferHistoriesByTxHash(ctx, common.HexToHash(txHash).Hex())
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve transfers for tx %s: %v", txHash, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if len(transfers) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfers)
+}
+
+// CancelTransaction cancels a pending reward transaction.
+// @Summary Cancel a pending transaction
+// @Description This admin endpoint replaces a pending reward transaction with a zero-value transfer to the reward account, using the same nonce and a bumped gas price.
+// @Tags transfer
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param txHash path string true "Transaction hash"
+// @Success 200 {object} map[string]string "Hash of the cancelling transaction: {\"transaction_hash\": \"0x...\"}"
+// @Failure 400 {object} util.GeneralError "Invalid transaction hash"
+// @Failure 409 {object} util.GeneralError "Transaction is not pending"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/transfer/transactions/{txHash}/cancel [post]
+func (h *TransferHandler) CancelTransaction(ctx *gin.Context) {
+	txHash := ctx.Param("txHash")
+	if len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction hash"})
+		return
+	}
+
+	cancelTxHash, err := h.UCase.CancelTransaction(ctx, common.HexToHash(txHash).Hex())
+	if err != nil {
+		if errors.Is(err, blockchain.ErrTransactionNotPending) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Transaction is not pending"})
+			return
+		}
+		log.LG.Errorf("Failed to cancel tx %s: %v", txHash, err)
 		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to distribute tokens",
+			"error":   "Failed to cancel transaction",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"transaction_hash": cancelTxHash})
+}
+
+// GetTransferHistories retrieves the transfer history, filtered and paginated.
+// @Summary Retrieve transfer history
+// @Description This endpoint returns the transfers matching the given filters, newest first. Dates are RFC 3339 timestamps or YYYY-MM-DD dates.
+// @Tags transfer
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param size query int false "Page size, between 4 and 50" default(10)
+// @Param recipient_address query string false "Recipient address"
+// @Param tx_type query string false "Transaction type (PURCHASE, COMMISSION or CANCEL)"
+// @Param status query int false "Status (-1 failed, 0 pending, 1 confirmed, 2 reverted, 3 dropped, 4 replaced)"
+// @Param tx_hash query string false "Transaction hash"
+// @Param from query string false "Only transfers created at or after this date"
+// @Param to query string false "Only transfers created before this date"
+// @Success 200 {object} dto.PaginationDTOResponse{data=[]dto.TransferHistoryDTO} "Successful retrieval of transfers"
+// @Failure 400 {object} util.GeneralError "Invalid filter"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/transfers [get]
+func (h *TransferHandler) GetTransferHistories(ctx *gin.Context) {
+	filter, err := parseTransferHistoryFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid filter",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"success": true})
+	page := ctx.GetInt(middleware.DEFAULT_PAGE_TEXT)
+	size := ctx.GetInt(middleware.DEFAULT_SIZE_TEXT)
+	response, err := h.UCase.GetTransferHistories(ctx, filter, page, size)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve transfers: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// GetTransferHistoryByID retrieves a single transfer.
+// @Summary Retrieve a transfer
+// @Description This endpoint returns the transfer with the given ID.
+// @Tags transfer
+// @Accept json
+// @Produce json
+// @Param id path int true "Transfer ID"
+// @Success 200 {object} dto.TransferHistoryDTO "Successful retrieval of the transfer"
+// @Failure 400 {object} util.GeneralError "Invalid transfer ID"
+// @Failure 404 {object} util.GeneralError "Transfer not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/transfers/{id} [get]
+func (h *TransferHandler) GetTransferHistoryByID(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return
+	}
+
+	transfer, err := h.UCase.GetTransferHistoryByID(ctx, id)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve transfer %d: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if transfer == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transfer)
+}
+
+// parseTransferHistoryFilter reads the transfer history filters from the query string.
+func parseTransferHistoryFilter(ctx *gin.Context) (dto.TransferHistoryFilterDTO, error) {
+	filter := dto.TransferHistoryFilterDTO{
+		TxType: ctx.Query("tx_type"),
+	}
+
+	if recipientAddress := ctx.Query("recipient_address"); recipientAddress != "" {
+		if !common.IsHexAddress(recipientAddress) {
+			return filter, fmt.Errorf("recipient_address must be a valid Ethereum address")
+		}
+		filter.RecipientAddress = recipientAddress
+	}
+
+	if status := ctx.Query("status"); status != "" {
+		value, err := strconv.ParseInt(status, 10, 16)
+		if err != nil {
+			return filter, fmt.Errorf("status must be an integer")
+		}
+		transferStatus := int16(value)
+		filter.Status = &transferStatus
+	}
+
+	if txHash := ctx.Query("tx_hash"); txHash != "" {
+		if len(txHash) != 66 || !strings.HasPrefix(txHash, "0x") {
+			return filter, fmt.Errorf("tx_hash must be a transaction hash")
+		}
+		filter.TransactionHash = common.HexToHash(txHash).Hex()
+	}
+
+	var err error
+	if filter.CreatedFrom, err = parseDateQuery(ctx, "from"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseDateQuery(ctx, "to"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseDateQuery parses an optional RFC 3339 timestamp or YYYY-MM-DD date from the query string.
+func parseDateQuery(ctx *gin.Context, name string) (*time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
 }
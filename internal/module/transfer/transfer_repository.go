@@ -2,12 +2,18 @@ package transfer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/dto"
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
 	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
 )
 
 type transferRepository struct {
@@ -20,10 +26,355 @@ func NewTransferRepository(db *gorm.DB) interfaces.TransferRepository {
 	}
 }
 
-func (r *transferRepository) CreateTransferHistories(ctx context.Context, models []model.TransferHistory) error {
-	err := r.db.WithContext(ctx).Create(&models).Error
+// SaveTransferHistories creates the new transfer histories and updates the existing ones, in a single transaction.
+func (r *transferRepository) SaveTransferHistories(ctx context.Context, models []model.TransferHistory) error {
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return saveTransferHistories(tx, models)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create transfer histories: %w", err)
+		return fmt.Errorf("failed to save transfer histories: %w", err)
+	}
+	return nil
+}
+
+// SaveSignedTransferHistories stores a signed transaction along with the transfer histories of its recipients,
+// in a single transaction. Existing transfer histories are updated.
+func (r *transferRepository) SaveSignedTransferHistories(ctx context.Context, signedTx *model.SignedTransaction, models []model.TransferHistory) error {
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(signedTx).Error; err != nil {
+			return err
+		}
+		return saveTransferHistories(tx, models)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save transfer histories of tx %s: %w", signedTx.TransactionHash, err)
+	}
+	return nil
+}
+
+// saveTransferHistories creates the transfer histories without an ID, and updates the submission of the others.
+func saveTransferHistories(tx *gorm.DB, models []model.TransferHistory) error {
+	var created []model.TransferHistory
+	for index := range models {
+		if models[index].ID == 0 {
+			created = append(created, models[index])
+			continue
+		}
+		if err := tx.Model(&model.TransferHistory{}).
+			Where("id = ?", models[index].ID).
+			Updates(submissionOf(models[index])).Error; err != nil {
+			return err
+		}
+	}
+	if len(created) == 0 {
+		return nil
+	}
+	return tx.Create(&created).Error
+}
+
+// submissionOf returns the columns set when a transfer is submitted, including the empty ones,
+// and with the empty fees as NULL like on creation.
+func submissionOf(m model.TransferHistory) map[string]interface{} {
+	nullIfEmpty := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+		return value
+	}
+	return map[string]interface{}{
+		"transaction_hash": m.TransactionHash,
+		"status":           m.Status,
+		"error_message":    m.ErrorMessage,
+		"batch_index":      m.BatchIndex,
+		"nonce":            m.Nonce,
+		"gas_limit":        m.GasLimit,
+		"gas_price":        nullIfEmpty(m.GasPrice),
+		"gas_tip_cap":      nullIfEmpty(m.GasTipCap),
+	}
+}
+
+// GetSignedTransaction retrieves the signed transaction with the given hash, or nil if it was not recorded.
+func (r *transferRepository) GetSignedTransaction(ctx context.Context, txHash string) (*model.SignedTransaction, error) {
+	var signedTx model.SignedTransaction
+	if err := unitofwork.DB(ctx, r.db).Where("transaction_hash = ?", txHash).First(&signedTx).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get signed transaction %s: %w", txHash, err)
+	}
+	return &signedTx, nil
+}
+
+// GetTransferHistoriesByStatus retrieves all transfer histories with the given status.
+func (r *transferRepository) GetTransferHistoriesByStatus(ctx context.Context, status int16) ([]model.TransferHistory, error) {
+	var transferHistories []model.TransferHistory
+	if err := unitofwork.DB(ctx, r.db).
+		Where("status = ?", status).
+		Order("id ASC").
+		Find(&transferHistories).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfer histories with status %d: %w", status, err)
+	}
+	return transferHistories, nil
+}
+
+// GetTransferHistoriesByTxHash retrieves the transfer histories of all recipients paid by the given transaction.
+func (r *transferRepository) GetTransferHistoriesByTxHash(ctx context.Context, txHash string) ([]model.TransferHistory, error) {
+	var transferHistories []model.TransferHistory
+	if err := unitofwork.DB(ctx, r.db).
+		Where("transaction_hash = ?", txHash).
+		Order("id ASC").
+		Find(&transferHistories).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfer histories for tx %s: %w", txHash, err)
+	}
+	return transferHistories, nil
+}
+
+// UpdateTransferHistoriesByTxHash applies the non-zero fields of update to all transfer histories of the given transaction.
+func (r *transferRepository) UpdateTransferHistoriesByTxHash(ctx context.Context, txHash string, update model.TransferHistory) error {
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.TransferHistory{}).
+		Where("transaction_hash = ?", txHash).
+		Updates(update).Error; err != nil {
+		return fmt.Errorf("failed to update transfer histories for tx %s: %w", txHash, err)
+	}
+	return nil
+}
+
+// ReplaceTransferHistories marks the transfer histories of a transaction as replaced by another one
+// and stores the replacement transaction with its transfer histories, in a single transaction.
+func (r *transferRepository) ReplaceTransferHistories(
+	ctx context.Context,
+	oldTxHash string,
+	replacement *model.SignedTransaction,
+	replacements []model.TransferHistory,
+) error {
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.TransferHistory{}).
+			Where("transaction_hash = ?", oldTxHash).
+			Updates(model.TransferHistory{
+				Status:         constants.TransferStatusReplaced,
+				ReplacedByHash: replacement.TransactionHash,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+		return tx.Create(&replacements).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace transfer histories of tx %s: %w", oldTxHash, err)
+	}
+	return nil
+}
+
+// RevertTransferReplacement undoes ReplaceTransferHistories for a replacement transaction that never reached
+// the node: the replacement and its transfer histories are deleted and those of the old transaction are pending again.
+func (r *transferRepository) RevertTransferReplacement(ctx context.Context, oldTxHash, newTxHash string) error {
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("transaction_hash = ?", newTxHash).Delete(&model.TransferHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("transaction_hash = ?", newTxHash).Delete(&model.SignedTransaction{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.TransferHistory{}).
+			Where("transaction_hash = ? AND replaced_by_hash = ?", oldTxHash, newTxHash).
+			Updates(map[string]interface{}{
+				"status":           constants.TransferStatusPending,
+				"replaced_by_hash": "",
+			}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revert replacement of tx %s by %s: %w", oldTxHash, newTxHash, err)
+	}
+	return nil
+}
+
+// GetTransferHistories retrieves a page of the transfer histories matching the filter, newest first,
+// along with the number of matching transfer histories across all pages.
+func (r *transferRepository) GetTransferHistories(
+	ctx context.Context,
+	filter dto.TransferHistoryFilterDTO,
+	limit, offset int,
+) ([]model.TransferHistory, int64, error) {
+	var total int64
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.TransferHistory{}).
+		Scopes(transferHistoryFilter(filter)).
+		Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count transfer histories: %w", err)
+	}
+
+	var transferHistories []model.TransferHistory
+	if err := unitofwork.DB(ctx, r.db).
+		Scopes(transferHistoryFilter(filter)).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&transferHistories).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get transfer histories: %w", err)
+	}
+	return transferHistories, total, nil
+}
+
+// transferHistoryFilter restricts a query to the transfer histories matching the filter.
+func transferHistoryFilter(filter dto.TransferHistoryFilterDTO) func(db *gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if filter.RecipientAddress != "" {
+			query = query.Where("LOWER(recipient_address) = LOWER(?)", filter.RecipientAddress)
+		}
+		if filter.TxType != "" {
+			query = query.Where("tx_type = ?", filter.TxType)
+		}
+		if filter.Status != nil {
+			query = query.Where("status = ?", *filter.Status)
+		}
+		if filter.TransactionHash != "" {
+			query = query.Where("transaction_hash = ?", filter.TransactionHash)
+		}
+		if filter.CreatedFrom != nil {
+			query = query.Where("created_at >= ?", *filter.CreatedFrom)
+		}
+		if filter.CreatedTo != nil {
+			query = query.Where("created_at < ?", *filter.CreatedTo)
+		}
+		return query
+	}
+}
+
+// GetTransferHistoryByID retrieves the transfer history with the given ID.
+func (r *transferRepository) GetTransferHistoryByID(ctx context.Context, id uint64) (*model.TransferHistory, error) {
+	var transferHistory model.TransferHistory
+	if err := unitofwork.DB(ctx, r.db).Where("id = ?", id).First(&transferHistory).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfer history %d: %w", id, err)
+	}
+	return &transferHistory, nil
+}
+
+// GetTransferHistoriesByBatchID retrieves the transfer histories created for the given distribution request.
+func (r *transferRepository) GetTransferHistoriesByBatchID(ctx context.Context, batchID string) ([]model.TransferHistory, error) {
+	var transferHistories []model.TransferHistory
+	if err := unitofwork.DB(ctx, r.db).
+		Where("batch_id = ?", batchID).
+		Order("id ASC").
+		Find(&transferHistories).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfer histories for batch %s: %w", batchID, err)
+	}
+	return transferHistories, nil
+}
+
+// HasPendingTransfersInNonceRange reports whether a pending transaction of the reward address
+// holds a nonce between fromNonce included and toNonce excluded.
+func (r *transferRepository) HasPendingTransfersInNonceRange(ctx context.Context, rewardAddress string, fromNonce, toNonce uint64) (bool, error) {
+	var count int64
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.TransferHistory{}).
+		Where("status = ? AND LOWER(reward_address) = LOWER(?) AND nonce >= ? AND nonce < ?",
+			constants.TransferStatusPending, rewardAddress, fromNonce, toNonce).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to count pending transfers of %s: %w", rewardAddress, err)
+	}
+	return count > 0, nil
+}
+
+// CreateTransferJob stores a new transfer job. It returns gorm.ErrDuplicatedKey
+// when a job with the same idempotency key already exists.
+func (r *transferRepository) CreateTransferJob(ctx context.Context, job *model.TransferJob) error {
+	result := unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "idempotency_key"}},
+			DoNothing: true,
+		}).
+		Create(job)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create transfer job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+// GetTransferJobByID retrieves the transfer job with the given ID.
+func (r *transferRepository) GetTransferJobByID(ctx context.Context, id string) (*model.TransferJob, error) {
+	var job model.TransferJob
+	if err := unitofwork.DB(ctx, r.db).Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfer job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+// GetTransferJobByIdempotencyKey retrieves the transfer job created with the given idempotency key.
+func (r *transferRepository) GetTransferJobByIdempotencyKey(ctx context.Context, idempotencyKey string) (*model.TransferJob, error) {
+	var job model.TransferJob
+	if err := unitofwork.DB(ctx, r.db).Where("idempotency_key = ?", idempotencyKey).First(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to get transfer job with idempotency key %s: %w", idempotencyKey, err)
+	}
+	return &job, nil
+}
+
+// ClaimTransferJob marks the next due transfer job as processing, counts the attempt and returns the job,
+// or nil when no job is due.
+// Jobs whose lock was not refreshed since before staleBefore are claimed again, as their worker is presumed dead.
+// Concurrent workers never claim the same job.
+func (r *transferRepository) ClaimTransferJob(ctx context.Context, staleBefore time.Time) (*model.TransferJob, error) {
+	var job model.TransferJob
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?)",
+				constants.TransferJobQueued, now, constants.TransferJobProcessing, staleBefore).
+			Order("next_attempt_at ASC").
+			First(&job).Error; err != nil {
+			return err
+		}
+
+		job.Status = constants.TransferJobProcessing
+		job.Attempts++
+		job.LockedAt = &now
+		return tx.Save(&job).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim transfer job: %w", err)
+	}
+	return &job, nil
+}
+
+// RefreshTransferJobLock moves the lock time of a job being processed to now, and reports whether the given
+// attempt still holds the job, as the attempts counter is increased whenever the job is claimed.
+func (r *transferRepository) RefreshTransferJobLock(ctx context.Context, id string, attempt int) (bool, error) {
+	result := unitofwork.DB(ctx, r.db).
+		Model(&model.TransferJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, constants.TransferJobProcessing, attempt).
+		Update("locked_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to refresh the lock of transfer job %s: %w", id, result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UpdateTransferJob saves the state of the transfer job.
+func (r *transferRepository) UpdateTransferJob(ctx context.Context, job *model.TransferJob) error {
+	if err := unitofwork.DB(ctx, r.db).Save(job).Error; err != nil {
+		return fmt.Errorf("failed to update transfer job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// UpdateReconciliationStatusByTxHashes sets the reconciliation status of the transfer histories of the given transactions.
+func (r *transferRepository) UpdateReconciliationStatusByTxHashes(ctx context.Context, txHashes []string, status int16) error {
+	if len(txHashes) == 0 {
+		return nil
+	}
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.TransferHistory{}).
+		Where("transaction_hash IN ?", txHashes).
+		Update("reconciliation_status", status).Error; err != nil {
+		return fmt.Errorf("failed to update reconciliation status: %w", err)
 	}
 	return nil
 }
@@ -2,10 +2,19 @@ package transfer
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
+	"time"
 
-	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/google/uuid"
 
 	"github.com/genefriendway/onchain-handler/blockchain"
 	"github.com/genefriendway/onchain-handler/conf"
@@ -13,43 +22,282 @@ import (
 	"github.com/genefriendway/onchain-handler/internal/dto"
 	"github.com/genefriendway/onchain-handler/internal/interfaces"
 	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultTransferJobMaxAttempts = 5                // Attempts after which a transfer job is marked as failed
+	TransferJobBaseBackoff        = 10 * time.Second // Delay before the first retry of a transfer job, doubled on each retry
+	TransferJobMaxBackoff         = 10 * time.Minute // Maximum delay between two attempts of a transfer job
+	TransferJobStaleTimeout       = 10 * time.Minute // Time after which a job left processing by a dead worker is claimed again
+	TransferJobHeartbeatInterval  = time.Minute      // Delay between two refreshes of the lock of a job being processed
 )
 
 type transferUCase struct {
 	TrasferRepository interfaces.TransferRepository
-	ETHClient         *ethclient.Client
+	ETHClient         blockchain.EthClient
 	Config            *conf.Configuration
+	NonceManager      *blockchain.NonceManager
+	Replacer          *blockchain.TransactionReplacer
+	FeeStrategy       blockchain.FeeStrategy
 }
 
-func NewtTransferUCase(transferRepository interfaces.TransferRepository, ethClient *ethclient.Client, config *conf.Configuration) interfaces.TransferUCase {
+func NewtTransferUCase(
+	transferRepository interfaces.TransferRepository,
+	ethClient blockchain.EthClient,
+	config *conf.Configuration,
+	nonceManager *blockchain.NonceManager,
+	replacer *blockchain.TransactionReplacer,
+	feeStrategy blockchain.FeeStrategy,
+) interfaces.TransferUCase {
 	return &transferUCase{
 		TrasferRepository: transferRepository,
 		ETHClient:         ethClient,
 		Config:            config,
+		NonceManager:      nonceManager,
+		Replacer:          replacer,
+		FeeStrategy:       feeStrategy,
+	}
+}
+
+var (
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different payload.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for another request")
+	// ErrInvalidTransferPayload is returned when the transfers of a request cannot be distributed as requested.
+	ErrInvalidTransferPayload = errors.New("invalid transfer payload")
+)
+
+// EnqueueTransfer queues a transfer job distributing the tokens of the payload. When an idempotency key is given,
+// a retry of an earlier request with the same key returns the job of that request instead of queueing a new one.
+func (u *transferUCase) EnqueueTransfer(ctx context.Context, idempotencyKey string, payloads []dto.TransferTokenPayloadDTO) (*dto.TransferJobDTO, error) {
+	// Reject invalid payloads now rather than in the worker
+	if _, err := u.convertToRecipients(payloads); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransferPayload, err)
+	}
+
+	encoded, err := json.Marshal(payloads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	requestHash := hashPayload(encoded)
+
+	job := &model.TransferJob{
+		ID:             uuid.NewString(),
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    requestHash,
+		Payload:        string(encoded),
+		Status:         constants.TransferJobQueued,
+		NextAttemptAt:  time.Now(),
+	}
+	err = u.TrasferRepository.CreateTransferJob(ctx, job)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return u.replayTransferJob(ctx, idempotencyKey, requestHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.LG.Infof("Queued transfer job %s for %d recipients", job.ID, len(payloads))
+	return u.toTransferJobDTO(ctx, job)
+}
+
+// replayTransferJob returns the job created by the earlier request with the same idempotency key.
+func (u *transferUCase) replayTransferJob(ctx context.Context, idempotencyKey, requestHash string) (*dto.TransferJobDTO, error) {
+	job, err := u.TrasferRepository.GetTransferJobByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if job.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	log.LG.Infof("Replaying transfer job %s for idempotency key %s", job.ID, idempotencyKey)
+	jobDTO, err := u.toTransferJobDTO(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	jobDTO.Replayed = true
+	return jobDTO, nil
+}
+
+// GetTransferJob retrieves the status of a transfer job and its transfers, or nil if the job does not exist.
+func (u *transferUCase) GetTransferJob(ctx context.Context, id string) (*dto.TransferJobDTO, error) {
+	job, err := u.TrasferRepository.GetTransferJobByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return u.toTransferJobDTO(ctx, job)
+}
+
+// toTransferJobDTO converts a transfer job, including the transfers created for it so far.
+func (u *transferUCase) toTransferJobDTO(ctx context.Context, job *model.TransferJob) (*dto.TransferJobDTO, error) {
+	transferHistories, err := u.TrasferRepository.GetTransferHistoriesByBatchID(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	jobDTO := job.ToDto()
+	jobDTO.Transfers = []dto.TransferHistoryDTO{}
+	for _, transferHistory := range transferHistories {
+		jobDTO.Transfers = append(jobDTO.Transfers, transferHistory.ToDto())
+	}
+	return &jobDTO, nil
+}
+
+// ProcessNextTransferJob claims the next due transfer job and distributes its tokens.
+// It reports whether a job was processed, so that workers only wait when the queue is empty.
+func (u *transferUCase) ProcessNextTransferJob(ctx context.Context) (bool, error) {
+	job, err := u.TrasferRepository.ClaimTransferJob(ctx, time.Now().Add(-TransferJobStaleTimeout))
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	maxAttempts := u.Config.TransferJobMaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultTransferJobMaxAttempts
+	}
+
+	// Refresh the lock while the job runs, so that a slow worker is not mistaken for a dead one
+	jobCtx, cancel := context.WithCancel(ctx)
+	go u.keepTransferJobLocked(jobCtx, cancel, job.ID, job.Attempts)
+	err = u.processTransferJob(jobCtx, job)
+	claimedElsewhere := jobCtx.Err() != nil && ctx.Err() == nil
+	cancel()
+	if claimedElsewhere {
+		// The state of the job now belongs to the worker that claimed it
+		return true, fmt.Errorf("transfer job %s was claimed by another worker: %v", job.ID, err)
+	}
+
+	job.LockedAt = nil
+	switch {
+	case err == nil:
+		job.Status = constants.TransferJobCompleted
+		job.LastError = ""
+		log.LG.Infof("Transfer job %s completed", job.ID)
+	case errors.Is(err, ErrInvalidTransferPayload) || job.Attempts >= maxAttempts:
+		job.Status = constants.TransferJobFailed
+		job.LastError = err.Error()
+		log.LG.Errorf("Transfer job %s failed after %d attempts: %v", job.ID, job.Attempts, err)
+	default:
+		job.Status = constants.TransferJobQueued
+		job.LastError = err.Error()
+		job.NextAttemptAt = time.Now().Add(transferJobBackoff(job.Attempts))
+		log.LG.Warnf("Transfer job %s attempt %d failed, retrying at %s: %v", job.ID, job.Attempts, job.NextAttemptAt, err)
+	}
+
+	return true, u.TrasferRepository.UpdateTransferJob(ctx, job)
+}
+
+// keepTransferJobLocked refreshes the lock of the given attempt of a job until the context is cancelled.
+// When another worker claimed the job in the meantime, the attempt is cancelled before it sends further batches.
+func (u *transferUCase) keepTransferJobLocked(ctx context.Context, cancel context.CancelFunc, jobID string, attempt int) {
+	ticker := time.NewTicker(TransferJobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		locked, err := u.TrasferRepository.RefreshTransferJobLock(ctx, jobID, attempt)
+		if err != nil {
+			log.LG.Warnf("Failed to refresh the lock of transfer job %s: %v", jobID, err)
+			continue
+		}
+		if !locked {
+			log.LG.Errorf("Transfer job %s was claimed by another worker, cancelling attempt %d", jobID, attempt)
+			cancel()
+			return
+		}
 	}
 }
 
-// DistributeTokens handles the entire process of tokens distribution
-func (u *transferUCase) DistributeTokens(ctx context.Context, payloads []dto.TransferTokenPayloadDTO) error {
+// processTransferJob distributes the tokens of the job to the recipients that were not submitted by an earlier attempt.
+func (u *transferUCase) processTransferJob(ctx context.Context, job *model.TransferJob) error {
+	var payloads []dto.TransferTokenPayloadDTO
+	if err := json.Unmarshal([]byte(job.Payload), &payloads); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTransferPayload, err)
+	}
+
+	payloads, failedIDs, err := u.unsubmittedPayloads(ctx, job.ID, payloads)
+	if err != nil {
+		return err
+	}
+	if len(payloads) == 0 {
+		return nil
+	}
+
 	// Convert the payload into recipients
 	recipients, err := u.convertToRecipients(payloads)
 	if err != nil {
-		return fmt.Errorf("failed to convert recipients: %v", err)
+		return fmt.Errorf("%w: failed to convert recipients: %v", ErrInvalidTransferPayload, err)
 	}
 
 	// Prepare reward history
 	rewardModels, err := u.prepareRewardHistory(payloads)
 	if err != nil {
-		return fmt.Errorf("failed to prepare reward history: %v", err)
+		return fmt.Errorf("%w: failed to prepare reward history: %v", ErrInvalidTransferPayload, err)
+	}
+	// The transfer histories left failed by earlier attempts are updated rather than duplicated
+	for index := range rewardModels {
+		rewardModels[index].ID = failedIDs[common.HexToAddress(rewardModels[index].RecipientAddress)]
 	}
 
-	// Perform concurrent reward distribution
-	err = u.distributeAndSaveRewards(ctx, rewardModels, recipients)
+	return u.distributeAndSaveRewards(ctx, job.ID, rewardModels, recipients)
+}
+
+// unsubmittedPayloads filters out the payloads whose transfer was already submitted in the given batch.
+// Transfers that were submitted are never retried, even if they were dropped later, so that no recipient is paid twice.
+// A submitted transaction that never reached the node is rebroadcast by the transaction tracker instead.
+// It also returns the ID of the latest failed transfer history of each recipient left to pay.
+func (u *transferUCase) unsubmittedPayloads(
+	ctx context.Context,
+	batchID string,
+	payloads []dto.TransferTokenPayloadDTO,
+) ([]dto.TransferTokenPayloadDTO, map[common.Address]uint64, error) {
+	transferHistories, err := u.TrasferRepository.GetTransferHistoriesByBatchID(ctx, batchID)
 	if err != nil {
-		return fmt.Errorf("failed to distribute rewards: %v", err)
+		return nil, nil, err
 	}
 
-	return nil
+	submitted := make(map[common.Address]bool)
+	failedIDs := make(map[common.Address]uint64)
+	for _, transferHistory := range transferHistories {
+		recipient := common.HexToAddress(transferHistory.RecipientAddress)
+		if transferHistory.Status != constants.TransferStatusFailed {
+			submitted[recipient] = true
+		} else {
+			failedIDs[recipient] = transferHistory.ID // Ordered by ID, so the latest one wins
+		}
+	}
+
+	var unsubmitted []dto.TransferTokenPayloadDTO
+	for _, payload := range payloads {
+		if !submitted[common.HexToAddress(payload.RecipientAddress)] {
+			unsubmitted = append(unsubmitted, payload)
+		}
+	}
+	return unsubmitted, failedIDs, nil
+}
+
+// transferJobBackoff returns the delay before the next attempt of a job that failed the given number of times.
+func transferJobBackoff(attempts int) time.Duration {
+	backoff := TransferJobBaseBackoff
+	for attempt := 1; attempt < attempts && backoff < TransferJobMaxBackoff; attempt++ {
+		backoff *= 2
+	}
+	if backoff > TransferJobMaxBackoff {
+		backoff = TransferJobMaxBackoff
+	}
+	return backoff
 }
 
 // convertToRecipients converts the payload into recipients (address -> token amount in smallest unit)
@@ -57,8 +305,14 @@ func (u *transferUCase) convertToRecipients(req []dto.TransferTokenPayloadDTO) (
 	recipients := make(map[string]*big.Int)
 
 	for _, payload := range req {
-		// Check for duplicate recipient addresses
-		if _, exists := recipients[payload.RecipientAddress]; exists {
+		// Jobs are processed asynchronously, so the addresses are validated here rather than only by the handler
+		if !common.IsHexAddress(payload.RecipientAddress) {
+			return nil, fmt.Errorf("invalid recipient address: %s", payload.RecipientAddress)
+		}
+
+		// Check for duplicate recipient addresses, whatever their casing
+		recipientAddress := common.HexToAddress(payload.RecipientAddress).Hex()
+		if _, exists := recipients[recipientAddress]; exists {
 			return nil, fmt.Errorf("duplicate recipient address: %s", payload.RecipientAddress)
 		}
 
@@ -70,7 +324,7 @@ func (u *transferUCase) convertToRecipients(req []dto.TransferTokenPayloadDTO) (
 
 		// Multiply by 10^18 to convert to the smallest unit of the token (like wei for ETH)
 		tokenAmountInSmallestUnit := new(big.Int).Mul(tokenAmount, new(big.Int).Exp(big.NewInt(10), big.NewInt(constants.LifePointDecimals), nil))
-		recipients[payload.RecipientAddress] = tokenAmountInSmallestUnit
+		recipients[recipientAddress] = tokenAmountInSmallestUnit
 	}
 
 	return recipients, nil
@@ -92,7 +346,7 @@ func (u *transferUCase) prepareRewardHistory(req []dto.TransferTokenPayloadDTO)
 			RewardAddress:    u.Config.Blockchain.RewardAddress,
 			RecipientAddress: payload.RecipientAddress,
 			TokenAmount:      payload.TokenAmount,
-			Status:           -1, // Default to failed status initially
+			Status:           constants.TransferStatusFailed, // Default to failed status initially
 			TxType:           payload.TxType,
 		})
 	}
@@ -100,24 +354,162 @@ func (u *transferUCase) prepareRewardHistory(req []dto.TransferTokenPayloadDTO)
 	return rewards, nil
 }
 
-// distributeAndSaveRewards distributes rewards and updates reward history
-func (u *transferUCase) distributeAndSaveRewards(ctx context.Context, rewards []model.TransferHistory, recipients map[string]*big.Int) error {
-	txHash, err := blockchain.DistributeReward(u.ETHClient, u.Config, recipients)
+// distributeAndSaveRewards distributes rewards and records the reward history. The transfers of each batch are
+// recorded as pending before its transaction is broadcast, so that neither a crash nor a retry pays a recipient twice.
+// It returns an error when any batch could not be sent, after recording the failed transfers.
+func (u *transferUCase) distributeAndSaveRewards(ctx context.Context, batchID string, rewards []model.TransferHistory, recipients map[string]*big.Int) error {
+	rewardOf := make(map[common.Address]*model.TransferHistory)
 	for index := range rewards {
+		rewards[index].BatchID = batchID
+		rewardOf[common.HexToAddress(rewards[index].RecipientAddress)] = &rewards[index]
+	}
+	recorded := make(map[common.Address]bool)
+
+	beforeSend := func(ctx context.Context, batch *blockchain.RewardBatch) error {
+		tx := batch.Transaction
+		batchRewards := make([]model.TransferHistory, 0, len(batch.Recipients))
+		for _, recipient := range batch.Recipients {
+			// The transaction tracker confirms the transfer once its receipt is available
+			reward := rewardOf[recipient]
+			reward.BatchIndex = batch.Index
+			reward.TransactionHash = tx.Hash().Hex()
+			reward.Nonce = tx.Nonce()
+			reward.GasLimit = tx.Gas()
+			reward.GasPrice = tx.GasFeeCap().String()
+			if tx.Type() == types.DynamicFeeTxType {
+				reward.GasTipCap = tx.GasTipCap().String()
+			}
+			reward.Status = constants.TransferStatusPending
+			batchRewards = append(batchRewards, *reward)
+		}
+
+		// The signed transaction is kept, so that the transaction tracker can rebroadcast it if it never reaches the node
+		signedTx, err := blockchain.NewSignedTransaction(tx)
 		if err != nil {
-			rewards[index].ErrorMessage = fmt.Sprintf("Failed to distribute: %v", err)
-			rewards[index].Status = -1
-		} else {
-			rewards[index].TransactionHash = *txHash
-			rewards[index].Status = 1
+			return err
+		}
+		if err := u.TrasferRepository.SaveSignedTransferHistories(ctx, signedTx, batchRewards); err != nil {
+			return err
 		}
+		for _, recipient := range batch.Recipients {
+			recorded[recipient] = true
+		}
+		return nil
 	}
 
-	// Save reward history
-	err = u.TrasferRepository.CreateTransferHistories(ctx, rewards)
+	batches, err := blockchain.DistributeReward(ctx, u.ETHClient, u.Config, u.NonceManager, u.FeeStrategy, recipients, beforeSend)
 	if err != nil {
-		return fmt.Errorf("failed to save rewards history: %v", err)
+		for index := range rewards {
+			rewards[index].ErrorMessage = fmt.Sprintf("Failed to distribute: %v", err)
+			rewards[index].Status = constants.TransferStatusFailed
+		}
+		if saveErr := u.TrasferRepository.SaveTransferHistories(ctx, rewards); saveErr != nil {
+			return fmt.Errorf("failed to save rewards history: %v", saveErr)
+		}
+		return fmt.Errorf("failed to distribute rewards: %w", err)
+	}
+
+	var failedRewards []model.TransferHistory
+	failedBatches := 0
+	for index := range batches {
+		batch := &batches[index]
+		if batch.Err == nil {
+			continue
+		}
+		failedBatches++
+
+		for _, recipient := range batch.Recipients {
+			if recorded[recipient] {
+				// The broadcast failed, but the transaction may still have reached the node,
+				// so the transfer stays pending until the transaction tracker settles it.
+				continue
+			}
+			reward := rewardOf[recipient]
+			reward.BatchIndex = batch.Index
+			reward.ErrorMessage = fmt.Sprintf("Failed to distribute: %v", batch.Err)
+			reward.Status = constants.TransferStatusFailed
+			failedRewards = append(failedRewards, *reward)
+		}
+	}
+
+	if len(failedRewards) > 0 {
+		if err := u.TrasferRepository.SaveTransferHistories(ctx, failedRewards); err != nil {
+			return fmt.Errorf("failed to save rewards history: %v", err)
+		}
+	}
+	if failedBatches > 0 {
+		return fmt.Errorf("failed to distribute %d of %d batches", failedBatches, len(batches))
 	}
 
 	return nil
 }
+
+// GetTransferHistoriesByTxHash retrieves the transfer histories of all recipients paid by the given transaction.
+func (u *transferUCase) GetTransferHistoriesByTxHash(ctx context.Context, txHash string) ([]dto.TransferHistoryDTO, error) {
+	transferHistories, err := u.TrasferRepository.GetTransferHistoriesByTxHash(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var transferHistoryDTOs []dto.TransferHistoryDTO
+	for _, transferHistory := range transferHistories {
+		transferHistoryDTOs = append(transferHistoryDTOs, transferHistory.ToDto())
+	}
+
+	return transferHistoryDTOs, nil
+}
+
+// GetTransferHistories retrieves a page of the transfer histories matching the filter, newest first.
+func (u *transferUCase) GetTransferHistories(ctx context.Context, filter dto.TransferHistoryFilterDTO, page, size int) (dto.PaginationDTOResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	transferHistories, total, err := u.TrasferRepository.GetTransferHistories(ctx, filter, size, (page-1)*size)
+	if err != nil {
+		return dto.PaginationDTOResponse{}, err
+	}
+
+	transferHistoryDTOs := []dto.TransferHistoryDTO{}
+	for _, transferHistory := range transferHistories {
+		transferHistoryDTOs = append(transferHistoryDTOs, transferHistory.ToDto())
+	}
+
+	nextPage := 0
+	if int64(page*size) < total {
+		nextPage = page + 1
+	}
+
+	return dto.PaginationDTOResponse{
+		Page:     page,
+		Size:     size,
+		Total:    total,
+		NextPage: nextPage,
+		Data:     transferHistoryDTOs,
+	}, nil
+}
+
+// GetTransferHistoryByID retrieves a transfer history, or nil if it does not exist.
+func (u *transferUCase) GetTransferHistoryByID(ctx context.Context, id uint64) (*dto.TransferHistoryDTO, error) {
+	transferHistory, err := u.TrasferRepository.GetTransferHistoryByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	transferHistoryDTO := transferHistory.ToDto()
+	return &transferHistoryDTO, nil
+}
+
+// CancelTransaction cancels a pending reward transaction and returns the hash of the cancelling transaction.
+func (u *transferUCase) CancelTransaction(ctx context.Context, txHash string) (string, error) {
+	return u.Replacer.Cancel(ctx, txHash)
+}
+
+// hashPayload returns the hex encoded SHA-256 of an encoded payload, used to recognize retries of a request.
+func hashPayload(encoded []byte) string {
+	hash := sha256.Sum256(encoded)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,74 @@
+package transfer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultTransferWorkers          = 2               // Number of workers processing transfer jobs
+	DefaultTransferJobsPollInterval = 2 * time.Second // Delay between two checks of an empty transfer job queue
+)
+
+// TransferWorker processes the queued transfer jobs with a pool of workers.
+type TransferWorker struct {
+	UCase        interfaces.TransferUCase
+	Workers      int
+	PollInterval time.Duration
+}
+
+// NewTransferWorker initializes the transfer worker pool.
+func NewTransferWorker(ucase interfaces.TransferUCase, workers int) *TransferWorker {
+	if workers <= 0 {
+		workers = DefaultTransferWorkers
+	}
+	return &TransferWorker{
+		UCase:        ucase,
+		Workers:      workers,
+		PollInterval: DefaultTransferJobsPollInterval,
+	}
+}
+
+// Run processes transfer jobs until the context is cancelled.
+func (worker *TransferWorker) Run(ctx context.Context) {
+	log.LG.Infof("Starting %d transfer workers...", worker.Workers)
+
+	var wg sync.WaitGroup
+	for index := 0; index < worker.Workers; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	log.LG.Info("Transfer workers stopped.")
+}
+
+// work processes jobs one after the other, and waits for the poll interval whenever the queue is empty.
+func (worker *TransferWorker) work(ctx context.Context) {
+	for {
+		processed, err := worker.UCase.ProcessNextTransferJob(ctx)
+		if err != nil {
+			log.LG.Errorf("Failed to process transfer job: %v", err)
+		}
+
+		if processed && err == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-time.After(worker.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package unitofwork
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+)
+
+type txKey struct{}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork creates a new UnitOfWork
+func NewUnitOfWork(db *gorm.DB) interfaces.UnitOfWork {
+	return &unitOfWork{
+		db: db,
+	}
+}
+
+// Do runs fn inside a database transaction. When ctx already carries a transaction,
+// fn joins it instead of starting a new one.
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// DB returns the transaction bound to ctx by a UnitOfWork, or db when there is none.
+// Repositories use it so that their calls take part in any surrounding unit of work.
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
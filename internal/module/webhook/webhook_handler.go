@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/middleware"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+type WebhookHandler struct {
+	UCase interfaces.WebhookUCase
+}
+
+// NewWebhookHandler initializes the WebhookHandler
+func NewWebhookHandler(ucase interfaces.WebhookUCase) *WebhookHandler {
+	return &WebhookHandler{
+		UCase: ucase,
+	}
+}
+
+// CreateSubscription registers a webhook subscriber.
+// @Summary Register a webhook subscriber
+// @Description This admin endpoint registers a URL receiving the events of a type (MembershipPurchased, TransferConfirmed or TransferReverted). Each request carries the X-Webhook-Signature header, "sha256=" followed by the hex HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" keyed with the subscription secret. The secret is generated when none is given, and is only returned by this endpoint.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param payload body dto.CreateWebhookSubscriptionPayloadDTO true "Event type, URL and optional secret of the subscriber"
+// @Success 201 {object} dto.WebhookSubscriptionDTO "Registered subscription, including its secret"
+// @Failure 400 {object} util.GeneralError "Invalid payload or unknown event type"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/webhooks/subscriptions [post]
+func (h *WebhookHandler) CreateSubscription(ctx *gin.Context) {
+	var req dto.CreateWebhookSubscriptionPayloadDTO
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid payload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	subscription, err := h.UCase.CreateSubscription(ctx, req)
+	if err != nil {
+		if errors.Is(err, ErrUnknownWebhookEventType) {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid event type",
+				"details": fmt.Sprintf("event_type must be one of %v", constants.WebhookEventTypes),
+			})
+			return
+		}
+		log.LG.Errorf("Failed to create webhook subscription: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, subscription)
+}
+
+// GetSubscriptions lists the webhook subscribers.
+// @Summary List webhook subscribers
+// @Description This admin endpoint returns every webhook subscription, active or not, without their secrets.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Success 200 {array} dto.WebhookSubscriptionDTO "Webhook subscriptions"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/webhooks/subscriptions [get]
+func (h *WebhookHandler) GetSubscriptions(ctx *gin.Context) {
+	subscriptions, err := h.UCase.GetSubscriptions(ctx)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve webhook subscriptions: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscriptions)
+}
+
+// DeleteSubscription deactivates a webhook subscriber.
+// @Summary Deactivate a webhook subscriber
+// @Description This admin endpoint stops the deliveries to a subscriber. Its delivery history is kept, and its queued deliveries are dead-lettered.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path int true "Subscription ID"
+// @Success 204 "Subscription deactivated"
+// @Failure 400 {object} util.GeneralError "Invalid subscription ID"
+// @Failure 404 {object} util.GeneralError "Active subscription not found"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/webhooks/subscriptions/{id} [delete]
+func (h *WebhookHandler) DeleteSubscription(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	deleted, err := h.UCase.DeleteSubscription(ctx, id)
+	if err != nil {
+		log.LG.Errorf("Failed to deactivate webhook subscription %d: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if !deleted {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetDeliveries lists the webhook deliveries.
+// @Summary List webhook deliveries
+// @Description This admin endpoint returns the webhook deliveries matching the given filters, newest first. Statuses are 0 pending, 1 delivering, 2 delivered and 3 dead letter.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param size query int false "Page size, between 4 and 50" default(10)
+// @Param subscription_id query int false "Subscription ID"
+// @Param event_type query string false "Event type"
+// @Param status query int false "Status (0 pending, 1 delivering, 2 delivered, 3 dead letter)"
+// @Success 200 {object} dto.PaginationDTOResponse{data=[]dto.WebhookDeliveryDTO} "Webhook deliveries"
+// @Failure 400 {object} util.GeneralError "Invalid filter"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/webhooks/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(ctx *gin.Context) {
+	filter, err := parseWebhookDeliveryFilter(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid filter",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	page := ctx.GetInt(middleware.DEFAULT_PAGE_TEXT)
+	size := ctx.GetInt(middleware.DEFAULT_SIZE_TEXT)
+	response, err := h.UCase.GetDeliveries(ctx, filter, page, size)
+	if err != nil {
+		log.LG.Errorf("Failed to retrieve webhook deliveries: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// ReplayDelivery queues a webhook delivery again.
+// @Summary Replay a webhook delivery
+// @Description This admin endpoint queues a delivery again with a fresh set of attempts, typically a dead-lettered one. The replayed request carries the same event ID, so that subscribers can ignore it if they already processed the event.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param X-Admin-Key header string true "Admin API key"
+// @Param id path int true "Delivery ID"
+// @Success 200 {object} dto.WebhookDeliveryDTO "Delivery queued again"
+// @Failure 400 {object} util.GeneralError "Invalid delivery ID"
+// @Failure 404 {object} util.GeneralError "Delivery not found"
+// @Failure 409 {object} util.GeneralError "Delivery is being delivered or was superseded"
+// @Failure 500 {object} util.GeneralError "Internal server error"
+// @Router /api/v1/admin/webhooks/deliveries/{id}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := h.UCase.ReplayDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrWebhookDeliveryInFlight) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Delivery is being delivered"})
+			return
+		}
+		if errors.Is(err, ErrWebhookDeliverySuperseded) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "Delivery was superseded", "details": err.Error()})
+			return
+		}
+		log.LG.Errorf("Failed to replay webhook delivery %d: %v", id, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if delivery == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// parseWebhookDeliveryFilter reads the webhook delivery filters from the query string.
+func parseWebhookDeliveryFilter(ctx *gin.Context) (dto.WebhookDeliveryFilterDTO, error) {
+	filter := dto.WebhookDeliveryFilterDTO{}
+
+	if subscriptionID := ctx.Query("subscription_id"); subscriptionID != "" {
+		value, err := strconv.ParseUint(subscriptionID, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("subscription_id must be a positive integer")
+		}
+		filter.SubscriptionID = value
+	}
+
+	if eventType := ctx.Query("event_type"); eventType != "" {
+		if !slices.Contains(constants.WebhookEventTypes, eventType) {
+			return filter, fmt.Errorf("event_type must be one of %v", constants.WebhookEventTypes)
+		}
+		filter.EventType = eventType
+	}
+
+	if status := ctx.Query("status"); status != "" {
+		value, err := strconv.ParseUint(status, 10, 8)
+		if err != nil {
+			return filter, fmt.Errorf("status must be an integer between 0 and 3")
+		}
+		deliveryStatus := uint8(value)
+		filter.Status = &deliveryStatus
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) interfaces.WebhookRepository {
+	return &webhookRepository{
+		db: db,
+	}
+}
+
+func (r *webhookRepository) CreateWebhookSubscription(ctx context.Context, subscription *model.WebhookSubscription) error {
+	if err := unitofwork.DB(ctx, r.db).Create(subscription).Error; err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetWebhookSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+	if err := unitofwork.DB(ctx, r.db).Order("id ASC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetWebhookSubscriptionByID retrieves a webhook subscription, or nil if it does not exist.
+func (r *webhookRepository) GetWebhookSubscriptionByID(ctx context.Context, id uint64) (*model.WebhookSubscription, error) {
+	var subscription model.WebhookSubscription
+	if err := unitofwork.DB(ctx, r.db).Where("id = ?", id).First(&subscription).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *webhookRepository) GetActiveWebhookSubscriptionsByEventType(ctx context.Context, eventType string) ([]model.WebhookSubscription, error) {
+	var subscriptions []model.WebhookSubscription
+	if err := unitofwork.DB(ctx, r.db).
+		Where("event_type = ? AND active = ?", eventType, true).
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// DeactivateWebhookSubscription stops queueing deliveries for the subscription, keeping its delivery history.
+// It reports whether an active subscription was found.
+func (r *webhookRepository) DeactivateWebhookSubscription(ctx context.Context, id uint64) (bool, error) {
+	result := unitofwork.DB(ctx, r.db).
+		Model(&model.WebhookSubscription{}).
+		Where("id = ? AND active = ?", id, true).
+		Update("active", false)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CreateWebhookDeliveries queues deliveries. A delivery of an event already queued for the same subscriber is ignored.
+func (r *webhookRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := unitofwork.DB(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}, {Name: "removed"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "NOT superseded"}}},
+			DoNothing:   true,
+		}).
+		Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("failed to create webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// SupersedeWebhookDeliveries stops the deliveries of an event removed from the chain by a reorg. The deliveries that
+// were never attempted are deleted, and the others are superseded. It returns the subscriptions whose subscriber may
+// have received the event, as they were attempted.
+func (r *webhookRepository) SupersedeWebhookDeliveries(ctx context.Context, eventID string) ([]uint64, error) {
+	var subscriptionIDs []uint64
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("event_id = ? AND NOT superseded AND status = ? AND attempts = 0", eventID, constants.WebhookDeliveryPending).
+			Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		var superseded []model.WebhookDelivery
+		if err := tx.
+			Model(&superseded).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "subscription_id"}, {Name: "removed"}}}).
+			Where("event_id = ? AND NOT superseded", eventID).
+			Update("superseded", true).Error; err != nil {
+			return err
+		}
+		for _, delivery := range superseded {
+			if !delivery.Removed {
+				subscriptionIDs = append(subscriptionIDs, delivery.SubscriptionID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to supersede webhook deliveries of event %s: %w", eventID, err)
+	}
+	return subscriptionIDs, nil
+}
+
+// ClaimWebhookDelivery marks the next due delivery as delivering, counts the attempt and returns the delivery,
+// or nil when no delivery is due.
+// Deliveries left delivering since before staleBefore are claimed again, as their worker is presumed dead.
+// Concurrent workers never claim the same delivery.
+func (r *webhookRepository) ClaimWebhookDelivery(ctx context.Context, staleBefore time.Time) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := unitofwork.DB(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("NOT superseded AND ((status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_at < ?))",
+				constants.WebhookDeliveryPending, now, constants.WebhookDeliveryDelivering, staleBefore).
+			Order("next_attempt_at ASC").
+			First(&delivery).Error; err != nil {
+			return err
+		}
+
+		delivery.Status = constants.WebhookDeliveryDelivering
+		delivery.Attempts++
+		delivery.LockedAt = &now
+		return tx.Save(&delivery).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// UpdateWebhookDelivery saves the state of the webhook delivery.
+func (r *webhookRepository) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := unitofwork.DB(ctx, r.db).Save(delivery).Error; err != nil {
+		return fmt.Errorf("failed to update webhook delivery %d: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveryByID retrieves a webhook delivery, or nil if it does not exist.
+func (r *webhookRepository) GetWebhookDeliveryByID(ctx context.Context, id uint64) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := unitofwork.DB(ctx, r.db).Where("id = ?", id).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries retrieves a page of the webhook deliveries matching the filter, newest first,
+// together with the number of deliveries matching the filter.
+func (r *webhookRepository) GetWebhookDeliveries(ctx context.Context, filter dto.WebhookDeliveryFilterDTO, limit, offset int) ([]model.WebhookDelivery, int64, error) {
+	var total int64
+	if err := unitofwork.DB(ctx, r.db).
+		Model(&model.WebhookDelivery{}).
+		Scopes(webhookDeliveryFilter(filter)).
+		Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	if err := unitofwork.DB(ctx, r.db).
+		Scopes(webhookDeliveryFilter(filter)).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// ReplayWebhookDelivery queues a delivery again with a fresh set of attempts, unless it is being delivered or
+// was superseded. It reports whether the delivery was queued.
+func (r *webhookRepository) ReplayWebhookDelivery(ctx context.Context, id uint64) (bool, error) {
+	result := unitofwork.DB(ctx, r.db).
+		Model(&model.WebhookDelivery{}).
+		Where("id = ? AND status <> ? AND NOT superseded", id, constants.WebhookDeliveryDelivering).
+		Updates(map[string]interface{}{
+			"status":          constants.WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"locked_at":       nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// webhookDeliveryFilter restricts a query to the webhook deliveries matching the filter.
+func webhookDeliveryFilter(filter dto.WebhookDeliveryFilterDTO) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.SubscriptionID != 0 {
+			db = db.Where("subscription_id = ?", filter.SubscriptionID)
+		}
+		if filter.EventType != "" {
+			db = db.Where("event_type = ?", filter.EventType)
+		}
+		if filter.Status != nil {
+			db = db.Where("status = ?", *filter.Status)
+		}
+		return db
+	}
+}
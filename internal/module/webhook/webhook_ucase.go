@@ -0,0 +1,336 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/constants"
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/model"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultWebhookMaxAttempts = 8                // Attempts after which a webhook delivery is dead-lettered
+	WebhookBaseBackoff        = 30 * time.Second // Delay before the first retry of a delivery, doubled on each retry
+	WebhookMaxBackoff         = 1 * time.Hour    // Maximum delay between two attempts of a delivery
+	WebhookStaleTimeout       = 5 * time.Minute  // Time after which a delivery left delivering by a dead worker is claimed again
+	WebhookRequestTimeout     = 10 * time.Second // Time a subscriber may take to answer
+	webhookSecretLength       = 32               // Length in bytes of the generated subscription secrets
+	maxWebhookErrorBodyLength = 512              // Length of the response body kept in the error of a failed attempt
+)
+
+var (
+	ErrUnknownWebhookEventType   = errors.New("unknown webhook event type")
+	ErrWebhookDeliveryInFlight   = errors.New("webhook delivery is being delivered")
+	ErrWebhookDeliverySuperseded = errors.New("webhook delivery was superseded by a retraction of its event")
+)
+
+type webhookUCase struct {
+	WebhookRepository interfaces.WebhookRepository
+	HTTPClient        *http.Client
+	MaxAttempts       int
+}
+
+func NewWebhookUCase(webhookRepository interfaces.WebhookRepository, maxAttempts int) interfaces.WebhookUCase {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultWebhookMaxAttempts
+	}
+	return &webhookUCase{
+		WebhookRepository: webhookRepository,
+		HTTPClient:        &http.Client{Timeout: WebhookRequestTimeout},
+		MaxAttempts:       maxAttempts,
+	}
+}
+
+// Publish queues a delivery of the event to every active subscriber of its type.
+func (u *webhookUCase) Publish(ctx context.Context, eventType, eventID string, data interface{}) error {
+	subscriptions, err := u.WebhookRepository.GetActiveWebhookSubscriptionsByEventType(ctx, eventType)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+
+	return u.queueDeliveries(ctx, subscriptions, eventType, eventID, false, data)
+}
+
+// Retract tells the subscribers of an event that a chain reorg removed it. The deliveries of the event that were never
+// attempted are cancelled, and the subscribers that may have received the event are sent it again with removed set.
+func (u *webhookUCase) Retract(ctx context.Context, eventType, eventID string, data interface{}) error {
+	subscriptionIDs, err := u.WebhookRepository.SupersedeWebhookDeliveries(ctx, eventID)
+	if err != nil {
+		return err
+	}
+
+	subscriptions, err := u.WebhookRepository.GetActiveWebhookSubscriptionsByEventType(ctx, eventType)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	var notified []model.WebhookSubscription
+	for _, subscription := range subscriptions {
+		if slices.Contains(subscriptionIDs, subscription.ID) {
+			notified = append(notified, subscription)
+		}
+	}
+
+	return u.queueDeliveries(ctx, notified, eventType, eventID, true, data)
+}
+
+// queueDeliveries queues a delivery of the event to every subscription. The body of the deliveries is built once,
+// so that each subscriber receives the same payload and event ID.
+func (u *webhookUCase) queueDeliveries(
+	ctx context.Context,
+	subscriptions []model.WebhookSubscription,
+	eventType, eventID string,
+	removed bool,
+	data interface{},
+) error {
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(dto.WebhookEventDTO{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Removed:   removed,
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	deliveries := make([]model.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, model.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventType:      eventType,
+			EventID:        eventID,
+			Payload:        string(body),
+			Status:         constants.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+			Removed:        removed,
+		})
+	}
+	return u.WebhookRepository.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+// CreateSubscription registers a subscriber URL for an event type. The secret of the subscription is only
+// returned here, and is generated when the payload does not provide one.
+func (u *webhookUCase) CreateSubscription(ctx context.Context, payload dto.CreateWebhookSubscriptionPayloadDTO) (*dto.WebhookSubscriptionDTO, error) {
+	if !slices.Contains(constants.WebhookEventTypes, payload.EventType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownWebhookEventType, payload.EventType)
+	}
+
+	secret := payload.Secret
+	if secret == "" {
+		key := make([]byte, webhookSecretLength)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(key)
+	}
+
+	subscription := &model.WebhookSubscription{
+		EventType: payload.EventType,
+		URL:       payload.URL,
+		Secret:    secret,
+		Active:    true,
+	}
+	if err := u.WebhookRepository.CreateWebhookSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	subscriptionDTO := subscription.ToDto()
+	subscriptionDTO.Secret = secret
+	return &subscriptionDTO, nil
+}
+
+func (u *webhookUCase) GetSubscriptions(ctx context.Context) ([]dto.WebhookSubscriptionDTO, error) {
+	subscriptions, err := u.WebhookRepository.GetWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptionDTOs := []dto.WebhookSubscriptionDTO{}
+	for _, subscription := range subscriptions {
+		subscriptionDTOs = append(subscriptionDTOs, subscription.ToDto())
+	}
+	return subscriptionDTOs, nil
+}
+
+// DeleteSubscription deactivates a subscription. Its queued deliveries are dead-lettered when their turn comes.
+// It reports whether an active subscription was found.
+func (u *webhookUCase) DeleteSubscription(ctx context.Context, id uint64) (bool, error) {
+	return u.WebhookRepository.DeactivateWebhookSubscription(ctx, id)
+}
+
+// GetDeliveries retrieves a page of the webhook deliveries matching the filter, newest first.
+func (u *webhookUCase) GetDeliveries(ctx context.Context, filter dto.WebhookDeliveryFilterDTO, page, size int) (dto.PaginationDTOResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	deliveries, total, err := u.WebhookRepository.GetWebhookDeliveries(ctx, filter, size, (page-1)*size)
+	if err != nil {
+		return dto.PaginationDTOResponse{}, err
+	}
+
+	deliveryDTOs := []dto.WebhookDeliveryDTO{}
+	for _, delivery := range deliveries {
+		deliveryDTOs = append(deliveryDTOs, delivery.ToDto())
+	}
+
+	nextPage := 0
+	if int64(page*size) < total {
+		nextPage = page + 1
+	}
+
+	return dto.PaginationDTOResponse{
+		Page:     page,
+		Size:     size,
+		Total:    total,
+		NextPage: nextPage,
+		Data:     deliveryDTOs,
+	}, nil
+}
+
+// ReplayDelivery queues a delivery again, whatever its outcome, with a fresh set of attempts. Superseded deliveries
+// are not replayed, as their event was removed from the chain. It returns nil if the delivery does not exist.
+func (u *webhookUCase) ReplayDelivery(ctx context.Context, id uint64) (*dto.WebhookDeliveryDTO, error) {
+	delivery, err := u.WebhookRepository.GetWebhookDeliveryByID(ctx, id)
+	if err != nil || delivery == nil {
+		return nil, err
+	}
+	if delivery.Superseded {
+		return nil, ErrWebhookDeliverySuperseded
+	}
+
+	replayed, err := u.WebhookRepository.ReplayWebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !replayed {
+		return nil, ErrWebhookDeliveryInFlight
+	}
+
+	delivery, err = u.WebhookRepository.GetWebhookDeliveryByID(ctx, id)
+	if err != nil || delivery == nil {
+		return nil, err
+	}
+	log.LG.Infof("Webhook delivery %d of event %s queued for replay", delivery.ID, delivery.EventID)
+
+	deliveryDTO := delivery.ToDto()
+	return &deliveryDTO, nil
+}
+
+// ProcessNextDelivery claims the next due webhook delivery and posts it to its subscriber.
+// It reports whether a delivery was processed, so that workers only wait when the queue is empty.
+func (u *webhookUCase) ProcessNextDelivery(ctx context.Context) (bool, error) {
+	delivery, err := u.WebhookRepository.ClaimWebhookDelivery(ctx, time.Now().Add(-WebhookStaleTimeout))
+	if err != nil {
+		return false, err
+	}
+	if delivery == nil {
+		return false, nil
+	}
+
+	subscription, err := u.WebhookRepository.GetWebhookSubscriptionByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		// Leave the delivery to be claimed again once it is stale
+		return true, fmt.Errorf("failed to get webhook subscription %d: %w", delivery.SubscriptionID, err)
+	}
+
+	delivery.LockedAt = nil
+	switch {
+	case subscription == nil || !subscription.Active:
+		delivery.Status = constants.WebhookDeliveryDeadLetter
+		delivery.LastError = "webhook subscription is no longer active"
+		log.LG.Warnf("Webhook delivery %d dead-lettered: subscription %d is no longer active", delivery.ID, delivery.SubscriptionID)
+	default:
+		delivery.ResponseStatus, err = u.deliver(ctx, subscription, delivery)
+		switch {
+		case err == nil:
+			now := time.Now()
+			delivery.Status = constants.WebhookDeliveryDelivered
+			delivery.LastError = ""
+			delivery.DeliveredAt = &now
+			log.LG.Debugf("Webhook delivery %d of event %s delivered to %s", delivery.ID, delivery.EventID, subscription.URL)
+		case delivery.Attempts >= u.MaxAttempts:
+			delivery.Status = constants.WebhookDeliveryDeadLetter
+			delivery.LastError = err.Error()
+			log.LG.Errorf("Webhook delivery %d dead-lettered after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+		default:
+			delivery.Status = constants.WebhookDeliveryPending
+			delivery.LastError = err.Error()
+			delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+			log.LG.Warnf("Webhook delivery %d attempt %d failed, retrying at %s: %v", delivery.ID, delivery.Attempts, delivery.NextAttemptAt, err)
+		}
+	}
+
+	return true, u.WebhookRepository.UpdateWebhookDelivery(ctx, delivery)
+}
+
+// deliver posts the payload of a delivery, signed with the secret of the subscription, and returns the HTTP status
+// of the response. Only a 2xx status acknowledges the delivery.
+func (u *webhookUCase) deliver(ctx context.Context, subscription *model.WebhookSubscription, delivery *model.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(constants.WebhookEventHeader, delivery.EventType)
+	req.Header.Set(constants.WebhookIDHeader, delivery.EventID)
+	req.Header.Set(constants.WebhookTimestampHeader, timestamp)
+	req.Header.Set(constants.WebhookSignatureHeader, "sha256="+signWebhookPayload(subscription.Secret, timestamp, body))
+
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBodyLength))
+		return resp.StatusCode, fmt.Errorf("webhook answered with status %d: %s", resp.StatusCode, responseBody)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the hex HMAC-SHA256 of "<timestamp>.<body>". Covering the timestamp lets
+// subscribers reject replayed requests.
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay before the next attempt of a delivery that failed the given number of times.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := WebhookBaseBackoff
+	for attempt := 1; attempt < attempts && backoff < WebhookMaxBackoff; attempt++ {
+		backoff *= 2
+	}
+	if backoff > WebhookMaxBackoff {
+		backoff = WebhookMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+const (
+	DefaultWebhookWorkers              = 2               // Number of workers delivering webhooks
+	DefaultWebhookDeliveryPollInterval = 2 * time.Second // Delay between two checks of an empty webhook delivery queue
+)
+
+// WebhookWorker delivers the queued webhook deliveries with a pool of workers.
+type WebhookWorker struct {
+	UCase        interfaces.WebhookUCase
+	Workers      int
+	PollInterval time.Duration
+}
+
+// NewWebhookWorker initializes the webhook worker pool.
+func NewWebhookWorker(ucase interfaces.WebhookUCase, workers int) *WebhookWorker {
+	if workers <= 0 {
+		workers = DefaultWebhookWorkers
+	}
+	return &WebhookWorker{
+		UCase:        ucase,
+		Workers:      workers,
+		PollInterval: DefaultWebhookDeliveryPollInterval,
+	}
+}
+
+// Run delivers webhooks until the context is cancelled.
+func (worker *WebhookWorker) Run(ctx context.Context) {
+	log.LG.Infof("Starting %d webhook workers...", worker.Workers)
+
+	var wg sync.WaitGroup
+	for index := 0; index < worker.Workers; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	log.LG.Info("Webhook workers stopped.")
+}
+
+// work processes deliveries one after the other, and waits for the poll interval whenever the queue is empty.
+func (worker *WebhookWorker) work(ctx context.Context) {
+	for {
+		processed, err := worker.UCase.ProcessNextDelivery(ctx)
+		if err != nil {
+			log.LG.Errorf("Failed to process webhook delivery: %v", err)
+		}
+
+		if processed && err == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-time.After(worker.PollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
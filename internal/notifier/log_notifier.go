@@ -0,0 +1,21 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/utils/log"
+)
+
+// LogNotifier only logs notifications. It is used when no webhook is configured.
+type LogNotifier struct{}
+
+func NewLogNotifier() interfaces.MembershipNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) NotifyMembershipExpiry(ctx context.Context, notification dto.MembershipExpiryNotificationDTO) error {
+	log.LG.Infof("Membership notification %s for %s, expiring at %s", notification.Type, notification.UserAddress, notification.ExpiresAt.Format("2006-01-02 15:04:05"))
+	return nil
+}
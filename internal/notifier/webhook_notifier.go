@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/genefriendway/onchain-handler/internal/dto"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+)
+
+// DefaultWebhookTimeout bounds the time a webhook receiver may take to answer.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// WebhookNotifier delivers notifications by POSTing them as JSON to a webhook URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookNotifier initializes a notifier posting to the given URL.
+func NewWebhookNotifier(url string) interfaces.MembershipNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: DefaultWebhookTimeout},
+	}
+}
+
+// NotifyMembershipExpiry posts the notification, and fails unless the receiver answers with a 2xx status.
+func (n *WebhookNotifier) NotifyMembershipExpiry(ctx context.Context, notification dto.MembershipExpiryNotificationDTO) error {
+	return n.post(ctx, notification)
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
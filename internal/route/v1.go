@@ -2,51 +2,234 @@ package route
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
 
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/genefriendway/onchain-handler/blockchain"
 	"github.com/genefriendway/onchain-handler/conf"
+	"github.com/genefriendway/onchain-handler/internal/interfaces"
+	"github.com/genefriendway/onchain-handler/internal/middleware"
 	"github.com/genefriendway/onchain-handler/internal/module/blockstate"
+	"github.com/genefriendway/onchain-handler/internal/module/contractevent"
+	"github.com/genefriendway/onchain-handler/internal/module/listener"
 	"github.com/genefriendway/onchain-handler/internal/module/membership"
+	"github.com/genefriendway/onchain-handler/internal/module/nonce"
+	"github.com/genefriendway/onchain-handler/internal/module/token"
 	"github.com/genefriendway/onchain-handler/internal/module/transfer"
+	"github.com/genefriendway/onchain-handler/internal/module/unitofwork"
+	"github.com/genefriendway/onchain-handler/internal/module/webhook"
+	"github.com/genefriendway/onchain-handler/internal/notifier"
 	"github.com/genefriendway/onchain-handler/internal/utils/log"
 )
 
-func RegisterRoutes(r *gin.Engine, config *conf.Configuration, db *gorm.DB, ethClient *ethclient.Client, ctx context.Context) {
+func RegisterRoutes(r *gin.Engine, config *conf.Configuration, db *gorm.DB, ethClient blockchain.EthClient, ctx context.Context) {
 	v1 := r.Group("/api/v1")
 	appRouter := v1.Group("")
+	adminRouter := v1.Group("/admin", middleware.AdminAuth(config.AdminKey))
+
+	unitOfWork := unitofwork.NewUnitOfWork(db)
+
+	// SECTION: webhooks
+	webhookUCase := webhook.NewWebhookUCase(webhook.NewWebhookRepository(db), config.WebhookMaxAttempts)
+	webhookHandler := webhook.NewWebhookHandler(webhookUCase)
+	adminRouter.POST("/webhooks/subscriptions", webhookHandler.CreateSubscription)
+	adminRouter.GET("/webhooks/subscriptions", webhookHandler.GetSubscriptions)
+	adminRouter.DELETE("/webhooks/subscriptions/:id", webhookHandler.DeleteSubscription)
+	adminRouter.GET("/webhooks/deliveries", webhookHandler.GetDeliveries)
+	adminRouter.POST("/webhooks/deliveries/:id/replay", webhookHandler.ReplayDelivery)
 
 	// SECTION: reward tokens
 	transferRepository := transfer.NewTransferRepository(db)
-	transferUCase := transfer.NewtTransferUCase(transferRepository, ethClient, config)
+	rewardNonceManager := blockchain.NewNonceManager(
+		ethClient,
+		nonce.NewNonceRepository(db),
+		transferRepository,
+		uint64(config.Blockchain.ChainID),
+		config.Blockchain.RewardAddress,
+	)
+	feeStrategy, err := blockchain.NewFeeStrategy(ctx, ethClient, config.Blockchain)
+	if err != nil {
+		log.LG.Errorf("Failed to initialize FeeStrategy: %v", err)
+		return
+	}
+	transactionReplacer, err := blockchain.NewTransactionReplacer(ethClient, transferRepository, feeStrategy, config)
+	if err != nil {
+		log.LG.Errorf("Failed to initialize TransactionReplacer: %v", err)
+		return
+	}
+	transferUCase := transfer.NewtTransferUCase(transferRepository, ethClient, config, rewardNonceManager, transactionReplacer, feeStrategy)
 	transferHandler := transfer.NewTransferHandler(transferUCase)
 	appRouter.POST("/transfer", transferHandler.Transfer)
+	appRouter.GET("/transfer/jobs/:id", transferHandler.GetTransferJob)
+	appRouter.GET("/transfer/transactions/:txHash", transferHandler.GetTransferHistoriesByTxHash)
+	appRouter.GET("/transfers", transferHandler.GetTransferHistories)
+	appRouter.GET("/transfers/:id", transferHandler.GetTransferHistoryByID)
+	adminRouter.POST("/transfer/transactions/:txHash/cancel", transferHandler.CancelTransaction)
 
 	// SECTION: membership purchase
 	membershipRepository := membership.NewMembershipRepository(db)
-	membershipUCase := membership.NewMembershipUCase(membershipRepository)
+	membershipContract, err := blockchain.NewMembershipContract(ethClient, config.Blockchain.MembershipContractAddress)
+	if err != nil {
+		log.LG.Errorf("Failed to initialize MembershipContract: %v", err)
+		return
+	}
+	var membershipNotifier interfaces.MembershipNotifier
+	if config.MembershipWebhookURL != "" {
+		membershipNotifier = notifier.NewWebhookNotifier(config.MembershipWebhookURL)
+	} else {
+		membershipNotifier = notifier.NewLogNotifier()
+	}
+	membershipUCase := membership.NewMembershipUCase(membershipRepository, membershipContract, membershipNotifier, config.MembershipExpiryLeadTimes)
 	membershipHandler := membership.NewMembershipHandler(membershipUCase)
 	appRouter.GET("/membership/events", membershipHandler.GetMembershipEventsByOrderIDs)
+	appRouter.GET("/membership/users/:address", membershipHandler.GetMembershipStatus)
+
+	// SECTION: token ledger
+	tokenRepository := token.NewTokenRepository(db)
+	tokenContract, err := blockchain.NewTokenContract(ethClient, config.Blockchain.LifePointAddress)
+	if err != nil {
+		log.LG.Errorf("Failed to initialize TokenContract: %v", err)
+		return
+	}
+	var tokenCache *redis.Client
+	if config.Redis.RedisAddress != "" {
+		tokenCache = conf.RedisConn()
+	}
+	tokenUCase := token.NewTokenUCase(tokenRepository, tokenContract, tokenCache, config)
+	tokenHandler := token.NewTokenHandler(tokenUCase)
+	appRouter.GET("/token/ledger/:address", tokenHandler.GetLedgerBalance)
+	appRouter.GET("/token/transfers", tokenHandler.GetTokenTransfers)
+	appRouter.GET("/token/balances/:address", tokenHandler.GetOnChainBalance)
+	appRouter.GET("/token/reward/balance", tokenHandler.GetRewardBalance)
+	appRouter.GET("/token/membership/balance", tokenHandler.GetMembershipContractBalance)
+	appRouter.GET("/token/supply", tokenHandler.GetTotalSupply)
+	appRouter.GET("/token/allowance", tokenHandler.GetAllowance)
+
+	// SECTION: transfer workers
+	transferWorker := transfer.NewTransferWorker(transferUCase, config.TransferWorkers)
+	go transferWorker.Run(ctx)
+
+	// SECTION: membership expiry scheduler
+	membershipExpiryScheduler := membership.NewMembershipExpiryScheduler(membershipUCase, config.MembershipExpiryInterval)
+	go membershipExpiryScheduler.Run(ctx)
+
+	// SECTION: webhook workers
+	webhookWorker := webhook.NewWebhookWorker(webhookUCase, config.WebhookWorkers)
+	go webhookWorker.Run(ctx)
+
+	// SECTION: transaction tracker
+	transactionTracker := blockchain.NewTransactionTracker(ethClient, transferRepository, unitOfWork, config.Blockchain.ConfirmationDepth)
+	transactionTracker.Webhooks = webhookUCase
+	go transactionTracker.Run(ctx)
+
+	// SECTION: transaction replacer
+	go transactionReplacer.Run(ctx)
 
 	// SECTION: events listener
-	membershipEventListener, err := blockchain.NewMembershipEventListener(
+	listenerRegistry, err := NewListenerRegistry(config, db, ethClient, unitOfWork, webhookUCase)
+	if err != nil {
+		log.LG.Errorf("Failed to initialize event listeners: %v", err)
+		return
+	}
+	go listenerRegistry.Run(ctx)
+
+	// SECTION: listener admin
+	listenerHandler := listener.NewListenerHandler(listener.NewListenerUCase(listenerRegistry))
+	adminRouter.GET("/listeners", listenerHandler.GetListeners)
+	adminRouter.POST("/listeners/:name/pause", listenerHandler.PauseListener)
+	adminRouter.POST("/listeners/:name/resume", listenerHandler.ResumeListener)
+	adminRouter.POST("/listeners/:name/rewind", listenerHandler.RewindListener)
+	adminRouter.POST("/listeners/:name/reset", listenerHandler.ResetListener)
+}
+
+// NewListenerRegistry creates the registry of the contract event listeners, which publish the indexed events
+// to the configured sinks. It is shared by the live listeners and the backfill command.
+func NewListenerRegistry(
+	config *conf.Configuration,
+	db *gorm.DB,
+	ethClient blockchain.EthClient,
+	unitOfWork interfaces.UnitOfWork,
+	webhookPublisher interfaces.WebhookPublisher,
+) (*blockchain.ListenerRegistry, error) {
+	eventSinks, err := newEventSinks(config, db, webhookPublisher)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize event sinks: %w", err)
+	}
+
+	listenerRegistry := blockchain.NewListenerRegistry(
 		ethClient,
-		config.Blockchain.MembershipContractAddress,
-		membershipRepository,
+		uint64(config.Blockchain.ChainID),
 		blockstate.NewBlockstateRepository(db),
+		unitOfWork,
 		&config.Blockchain.StartBlockListener,
+		config.Blockchain.ConfirmationDepth,
+		eventSinks,
 	)
-	if err != nil {
-		log.LG.Errorf("Failed to initialize MembershipEventListener: %v", err)
-		return
+	listenerRegistry.MinBlockRange = config.Blockchain.MinBlockRange
+	listenerRegistry.MaxBlockRange = config.Blockchain.MaxBlockRange
+	// Listeners are woken up by log subscriptions over WebSocket endpoints, and poll otherwise
+	for _, rpcURL := range config.Blockchain.RpcEndpoints() {
+		if blockchain.IsWebSocketURL(rpcURL) {
+			listenerRegistry.SubscribeLogs = true
+		}
+	}
+
+	membershipEventListener := blockchain.NewMembershipEventListener(membership.NewMembershipRepository(db))
+	if _, err := listenerRegistry.Register(membershipEventListener.Definition(config.Blockchain.MembershipContractAddress)); err != nil {
+		return nil, fmt.Errorf("failed to initialize MembershipEventListener: %w", err)
 	}
-	go func() {
-		if err := membershipEventListener.RunListener(ctx); err != nil {
-			log.LG.Errorf("Error running MembershipEventListener: %v", err)
+	tokenEventListener := blockchain.NewTokenEventListener(
+		token.NewTokenRepository(db),
+		transfer.NewTransferRepository(db),
+		config.Blockchain.LifePointAddress,
+	)
+	if _, err := listenerRegistry.Register(tokenEventListener.Definition(tokenStartBlock(config, ethClient))); err != nil {
+		return nil, fmt.Errorf("failed to initialize TokenEventListener: %w", err)
+	}
+	return listenerRegistry, nil
+}
+
+// tokenStartBlock returns the first block indexed by the token listener: the configured one, or else the
+// deployment block of the token, so that the balances account for every transfer.
+func tokenStartBlock(config *conf.Configuration, ethClient blockchain.EthClient) blockchain.StartBlockFunc {
+	return func(ctx context.Context) (uint64, error) {
+		if config.Blockchain.TokenStartBlockListener > 0 {
+			return config.Blockchain.TokenStartBlockListener, nil
+		}
+		deploymentBlock, err := blockchain.FindDeploymentBlock(ctx, ethClient, config.Blockchain.LifePointAddress)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find the deployment block of the token, set TOKEN_START_BLOCK_LISTENER instead: %w", err)
+		}
+		return deploymentBlock, nil
+	}
+}
+
+// newEventSinks creates the sinks the listeners publish the indexed events to. Events go to webhooks
+// when no sink is configured.
+func newEventSinks(config *conf.Configuration, db *gorm.DB, webhookPublisher interfaces.WebhookPublisher) ([]blockchain.EventSink, error) {
+	sinkNames := config.EventSinks
+	if len(sinkNames) == 0 {
+		sinkNames = []string{"webhook"}
+	}
+
+	var sinks []blockchain.EventSink
+	for _, sinkName := range sinkNames {
+		switch strings.ToLower(strings.TrimSpace(sinkName)) {
+		case "postgres":
+			sinks = append(sinks, blockchain.NewPostgresEventSink(contractevent.NewContractEventRepository(db)))
+		case "redis":
+			sinks = append(sinks, blockchain.NewRedisStreamEventSink(conf.RedisConn(), config.Redis.RedisStreamPrefix, config.Redis.RedisStreamMaxLen))
+		case "webhook":
+			sinks = append(sinks, blockchain.NewWebhookEventSink(webhookPublisher))
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", sinkName)
 		}
-	}()
+	}
+	return sinks, nil
 }
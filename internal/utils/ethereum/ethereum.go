@@ -1,7 +1,6 @@
 package ethereum
 
 import (
-	"context"
 	"crypto/ecdsa"
 	"errors"
 	"fmt"
@@ -32,28 +31,17 @@ func GetFromAddress(privateKey *ecdsa.PrivateKey) (string, error) {
 	return fromAddress.Hex(), nil
 }
 
-// GetAuth creates a new keyed transactor for signing transactions with the given private key and network chain ID
-func GetAuth(client *ethclient.Client, privateKey *ecdsa.PrivateKey, chainID *big.Int) (*bind.TransactOpts, error) {
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get nonce: %w", err)
-	}
-
-	gasPrice, err := client.SuggestGasPrice(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get gas price: %w", err)
-	}
-
+// GetAuth creates a new keyed transactor for signing transactions with the given private key, network chain ID and nonce.
+// The fee fields are left for the caller to set.
+func GetAuth(privateKey *ecdsa.PrivateKey, chainID *big.Int, nonce uint64) (*bind.TransactOpts, error) {
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transactor: %w", err)
 	}
 
-	auth.Nonce = big.NewInt(int64(nonce))
+	auth.Nonce = new(big.Int).SetUint64(nonce)
 	auth.Value = big.NewInt(0)     // 0 wei, since we're not sending Ether
 	auth.GasLimit = uint64(300000) // Set the gas limit (adjust as needed)
-	auth.GasPrice = gasPrice       // Set the gas price
 
 	return auth, nil
 }
@@ -0,0 +1,11 @@
+CREATE TABLE processed_block (
+    block_number BIGINT PRIMARY KEY,        -- Number of a recently processed block
+    block_hash VARCHAR(66) NOT NULL,        -- Hash of the block at the time it was processed
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE membership_event
+    ADD COLUMN block_number BIGINT NOT NULL DEFAULT 0,  -- Block that included the event
+    ADD COLUMN block_hash VARCHAR(66) NOT NULL DEFAULT ''; -- Hash of the block that included the event
+
+CREATE INDEX membership_event_block_number_idx ON membership_event (block_number);
@@ -0,0 +1,21 @@
+-- Key the block cursor by listener so that several listeners can progress independently.
+-- A pre-existing row keeps a NULL listener_key and is claimed by the membership listener when it is registered.
+ALTER TABLE block_state
+    ADD COLUMN listener_key VARCHAR(255) UNIQUE,     -- "<chain_id>:<contract_address>:<event_set>"
+    ADD COLUMN chain_id BIGINT,
+    ADD COLUMN contract_address VARCHAR(50),
+    ADD COLUMN event_set TEXT,                       -- Comma-separated, sorted list of indexed events
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TRIGGER update_block_state_updated_at
+BEFORE UPDATE ON block_state
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Block hashes are kept per listener as well. Hashes stored so far cannot be attributed to a listener.
+TRUNCATE TABLE processed_block;
+
+ALTER TABLE processed_block
+    DROP CONSTRAINT processed_block_pkey,
+    ADD COLUMN listener_key VARCHAR(255) NOT NULL,
+    ADD PRIMARY KEY (listener_key, block_number);
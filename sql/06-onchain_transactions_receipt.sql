@@ -0,0 +1,9 @@
+-- Receipt data of reward transactions, filled in by the transaction tracker once they are mined.
+-- status: -1 failed to submit, 0 pending, 1 confirmed, 2 reverted, 3 dropped
+ALTER TABLE onchain_transactions
+    ADD COLUMN block_number BIGINT,
+    ADD COLUMN gas_used BIGINT,
+    ADD COLUMN effective_gas_price NUMERIC(78, 0);
+
+CREATE INDEX onchain_transactions_status_idx ON onchain_transactions (status);
+CREATE INDEX onchain_transactions_transaction_hash_idx ON onchain_transactions (transaction_hash);
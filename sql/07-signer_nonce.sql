@@ -0,0 +1,12 @@
+CREATE TABLE signer_nonce (
+    chain_id BIGINT NOT NULL,
+    address VARCHAR(50) NOT NULL,          -- Address of the signing account
+    next_nonce BIGINT NOT NULL,            -- Next nonce to hand out for the account
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chain_id, address)
+);
+
+CREATE TRIGGER update_signer_nonce_updated_at
+BEFORE UPDATE ON signer_nonce
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
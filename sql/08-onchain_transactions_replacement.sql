@@ -0,0 +1,10 @@
+-- Replacement chain of stuck reward transactions.
+-- A replaced transaction keeps its rows with status 4 (replaced) and points to its replacement,
+-- whose rows point back through replaces_hash.
+ALTER TABLE onchain_transactions
+    ADD COLUMN nonce BIGINT,
+    ADD COLUMN gas_price NUMERIC(78, 0),
+    ADD COLUMN replaces_hash VARCHAR(66),      -- Transaction replaced by this one, if any
+    ADD COLUMN replaced_by_hash VARCHAR(66);   -- Transaction that replaced this one, if any
+
+CREATE INDEX onchain_transactions_replaces_hash_idx ON onchain_transactions (replaces_hash);
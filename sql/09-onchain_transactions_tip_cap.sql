@@ -0,0 +1,3 @@
+-- For EIP-1559 transactions gas_price holds the max fee per gas and gas_tip_cap the max priority fee per gas.
+ALTER TABLE onchain_transactions
+    ADD COLUMN gas_tip_cap NUMERIC(78, 0);
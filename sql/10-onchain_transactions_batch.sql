@@ -0,0 +1,8 @@
+-- Bulk transfers are split into several transactions that fit under the gas budget.
+-- All rows of one distribution request share batch_id, and batch_index tells which transaction paid them.
+ALTER TABLE onchain_transactions
+    ADD COLUMN batch_id VARCHAR(36),
+    ADD COLUMN batch_index INT NOT NULL DEFAULT 0,
+    ADD COLUMN gas_limit BIGINT;
+
+CREATE INDEX onchain_transactions_batch_id_idx ON onchain_transactions (batch_id);
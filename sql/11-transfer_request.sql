@@ -0,0 +1,17 @@
+-- Idempotency keys of token distribution requests.
+-- A retried request with the same key is answered from the transfer histories of the original batch.
+CREATE TABLE transfer_request (
+    id SERIAL PRIMARY KEY,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,     -- SHA-256 of the request payload
+    batch_id VARCHAR(36),                  -- batch_id of the onchain_transactions rows created for the request
+    status SMALLINT NOT NULL DEFAULT 0,    -- 0 for processing, 1 for completed
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_idempotency_key UNIQUE (idempotency_key)
+);
+
+CREATE TRIGGER update_transfer_request_updated_at
+BEFORE UPDATE ON transfer_request
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,41 @@
+-- Outbox of token distribution requests, processed by the transfer workers.
+-- The job id is also the batch_id of the onchain_transactions rows created for the job.
+CREATE TABLE transfer_job (
+    id VARCHAR(36) PRIMARY KEY,
+    idempotency_key VARCHAR(255),
+    request_hash VARCHAR(64) NOT NULL,         -- SHA-256 of the request payload
+    payload JSONB NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 0,        -- 0 for queued, 1 for processing, 2 for completed, 3 for failed
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP,                       -- Time a worker started processing the job
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_transfer_job_idempotency_key UNIQUE (idempotency_key)
+);
+
+CREATE INDEX transfer_job_status_next_attempt_at_idx ON transfer_job (status, next_attempt_at);
+
+CREATE TRIGGER update_transfer_job_updated_at
+BEFORE UPDATE ON transfer_job
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Idempotency keys now live on the jobs. Requests completed synchronously before become completed jobs,
+-- so that their retries keep being answered with the original transfers. Their payload was not stored.
+INSERT INTO transfer_job (id, idempotency_key, request_hash, payload, status, created_at, updated_at)
+SELECT batch_id, idempotency_key, request_hash, '[]', 2, created_at, updated_at
+FROM transfer_request
+WHERE status = 1;
+
+-- Requests still processing were interrupted by the upgrade. Neither their payload nor the batch of the transfers
+-- they may have sent was stored, so they become failed jobs: their retries are answered with the failure instead
+-- of being reported as completed or distributed again.
+INSERT INTO transfer_job (id, idempotency_key, request_hash, payload, status, last_error, created_at, updated_at)
+SELECT md5(idempotency_key)::uuid::text, idempotency_key, request_hash, '[]', 3,
+       'Interrupted by the upgrade to transfer jobs, some transfers may have been sent', created_at, updated_at
+FROM transfer_request
+WHERE status = 0;
+
+DROP TABLE transfer_request;
@@ -0,0 +1,3 @@
+-- Indexes backing the transfer history query API.
+CREATE INDEX onchain_transactions_recipient_address_idx ON onchain_transactions (LOWER(recipient_address));
+CREATE INDEX onchain_transactions_created_at_idx ON onchain_transactions (created_at);
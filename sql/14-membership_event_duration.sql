@@ -0,0 +1,10 @@
+-- Duration type of the purchase as emitted by MembershipPurchased: 0 for 1 year, 1 for 3 years.
+-- Existing rows are backfilled from the period between indexing and expiry.
+ALTER TABLE membership_event
+    ADD COLUMN duration SMALLINT NOT NULL DEFAULT 0;
+
+UPDATE membership_event
+SET duration = 1
+WHERE end_duration > created_at + INTERVAL '2 years';
+
+CREATE INDEX membership_event_user_address_idx ON membership_event (user_address);
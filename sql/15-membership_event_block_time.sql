@@ -0,0 +1,6 @@
+-- Position and time of the log in the chain. Expiry is computed from the block timestamp, so that events
+-- indexed late or backfilled get the same expiry as events indexed right away.
+-- Rows indexed before this migration keep a NULL block_timestamp and fall back to created_at.
+ALTER TABLE membership_event
+    ADD COLUMN block_timestamp TIMESTAMP,
+    ADD COLUMN log_index INT NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+-- Membership expiry notifications that were delivered, so that each one is sent once per expiry and lead time.
+-- A renewal moves the expiry of the membership, which makes the notifications of the new expiry due again.
+CREATE TABLE membership_notification (
+    id BIGSERIAL PRIMARY KEY,
+    user_address VARCHAR(50) NOT NULL,
+    notification_type VARCHAR(50) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    lead_time BIGINT NOT NULL DEFAULT 0, -- Lead time of an expiring soon notification in seconds, 0 for expired
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT membership_notification_unique UNIQUE (user_address, notification_type, expires_at, lead_time)
+);
@@ -0,0 +1,43 @@
+-- Webhook subscribers, registered per event type.
+CREATE TABLE webhook_subscription (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,          -- e.g. MembershipPurchased, TransferConfirmed
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL,              -- Key of the HMAC-SHA256 signature of the payloads
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX webhook_subscription_event_type_idx ON webhook_subscription (event_type);
+
+CREATE TRIGGER update_webhook_subscription_updated_at
+BEFORE UPDATE ON webhook_subscription
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Delivery of an event to a subscriber, retried with exponential backoff until it is dead-lettered.
+CREATE TABLE webhook_delivery (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscription (id),
+    event_type VARCHAR(100) NOT NULL,
+    event_id VARCHAR(100) NOT NULL,            -- Identifies the event, so that receivers can ignore duplicates
+    payload JSONB NOT NULL,                    -- Body posted to the subscriber
+    status SMALLINT NOT NULL DEFAULT 0,        -- 0 for pending, 1 for delivering, 2 for delivered, 3 for dead letter
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    response_status INT NOT NULL DEFAULT 0,    -- HTTP status of the last attempt, 0 if there was no response
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_at TIMESTAMP,                       -- Time a worker started the current attempt
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_webhook_delivery_event UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_delivery_status_next_attempt_at_idx ON webhook_delivery (status, next_attempt_at);
+
+CREATE TRIGGER update_webhook_delivery_updated_at
+BEFORE UPDATE ON webhook_delivery
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
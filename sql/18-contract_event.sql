@@ -0,0 +1,10 @@
+-- Events published by the listeners to the postgres event sink, for downstream services.
+-- Each insert notifies the contract_event channel with the id of the new row.
+CREATE TABLE contract_event (
+    id BIGSERIAL PRIMARY KEY,
+    event_name VARCHAR(100) NOT NULL,          -- e.g. MembershipPurchased
+    event_id VARCHAR(100) NOT NULL,            -- Identifies the event, e.g. its transaction hash
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_contract_event UNIQUE (event_name, event_id)
+);
@@ -0,0 +1,42 @@
+-- LifePoint token transfers indexed from the Transfer events of the token contract.
+CREATE TABLE token_transfer (
+    id BIGSERIAL PRIMARY KEY,
+    token_address VARCHAR(50) NOT NULL,
+    from_address VARCHAR(50) NOT NULL,
+    to_address VARCHAR(50) NOT NULL,
+    amount NUMERIC(78, 0) NOT NULL,            -- In the smallest unit of the token
+    transaction_hash VARCHAR(66) NOT NULL,
+    log_index INT NOT NULL,
+    block_number BIGINT NOT NULL,
+    block_hash VARCHAR(66) NOT NULL,
+    block_timestamp TIMESTAMP NOT NULL,
+    status SMALLINT NOT NULL DEFAULT 1,        -- 1 for success, 2 for orphaned by a chain reorg
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_token_transfer_log UNIQUE (transaction_hash, log_index)
+);
+
+CREATE INDEX token_transfer_from_address_idx ON token_transfer (from_address);
+CREATE INDEX token_transfer_to_address_idx ON token_transfer (to_address);
+CREATE INDEX token_transfer_block_number_idx ON token_transfer (block_number);
+
+CREATE TRIGGER update_token_transfer_updated_at
+BEFORE UPDATE ON token_transfer
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Balance of each address, computed from the indexed transfers. It only accounts for the transfers
+-- indexed since the listener started, so the listener must start at the deployment block of the token.
+CREATE TABLE token_balance (
+    token_address VARCHAR(50) NOT NULL,
+    address VARCHAR(50) NOT NULL,
+    balance NUMERIC(78, 0) NOT NULL DEFAULT 0, -- In the smallest unit of the token
+    last_block BIGINT NOT NULL DEFAULT 0,      -- Block of the last transfer applied to the balance
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (token_address, address)
+);
+
+-- Outcome of the comparison of our reward transactions with the BulkTransfer events of the token contract:
+-- 0 for not reconciled yet, 1 for matched, 2 for mismatched.
+ALTER TABLE onchain_transactions
+    ADD COLUMN reconciliation_status SMALLINT NOT NULL DEFAULT 0;
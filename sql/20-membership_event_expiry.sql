@@ -0,0 +1,2 @@
+-- Candidates of the membership expiry scheduler are looked up by the latest end of the successful purchases of each user.
+CREATE INDEX membership_event_status_user_address_end_duration_idx ON membership_event (status, user_address, end_duration);
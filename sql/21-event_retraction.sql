@@ -0,0 +1,30 @@
+-- Events published by each listener from its recent blocks, so that they can be retracted from the sinks when a
+-- chain reorg replaces their block. Like the block hashes, only the events of the retained blocks are kept.
+CREATE TABLE published_event (
+    listener_key VARCHAR(255) NOT NULL,
+    event_name VARCHAR(100) NOT NULL,
+    event_id VARCHAR(100) NOT NULL,
+    block_number BIGINT NOT NULL,              -- Block that included the event
+    payload JSONB NOT NULL,                    -- Payload the event was published with
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (listener_key, event_name, event_id)
+);
+
+CREATE INDEX published_event_listener_key_block_number_idx ON published_event (listener_key, block_number);
+
+-- A retracted event is published again with removed set, e.g. as a new contract_event row. The rows of the event
+-- published before are superseded, so that the event can be published again if another block includes it.
+ALTER TABLE contract_event
+    DROP CONSTRAINT unique_contract_event,
+    ADD COLUMN removed BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN superseded BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX unique_contract_event ON contract_event (event_name, event_id, removed) WHERE NOT superseded;
+
+-- Superseded deliveries are no longer attempted.
+ALTER TABLE webhook_delivery
+    DROP CONSTRAINT unique_webhook_delivery_event,
+    ADD COLUMN removed BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN superseded BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX unique_webhook_delivery_event ON webhook_delivery (subscription_id, event_id, removed) WHERE NOT superseded;
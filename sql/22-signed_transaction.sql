@@ -0,0 +1,7 @@
+-- Signed reward transactions, recorded before their broadcast so that the transaction tracker can rebroadcast
+-- a transaction that never reached the node instead of dropping it with its recipients unpaid.
+CREATE TABLE signed_transaction (
+    transaction_hash VARCHAR(66) PRIMARY KEY,
+    raw_transaction BYTEA NOT NULL,            -- Binary encoding of the signed transaction
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);